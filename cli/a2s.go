@@ -1,10 +1,79 @@
 package main
 
 import (
+	"fmt"
+	"strconv"
+
 	"github.com/rs/zerolog/log"
 	"github.com/woozymasta/a2s/pkg/a2s"
+	"github.com/woozymasta/a2s/pkg/a3sb"
+	"github.com/woozymasta/a2s/pkg/keywords"
+	"github.com/woozymasta/steam/utils/appid"
 )
 
+// rulesBufferSize is the minimal buffer size for A2S_RULES, responses are usually large and multi-packet
+const rulesBufferSize uint16 = 8192
+
+/*
+query queries the server and returns the template data with the number of players in the queue.
+
+Players and rules are queried only if enabled, their failures are logged and do not
+make the server offline. If A2S_INFO fails, the template data without Info is returned
+together with the error.
+*/
+func (s *ServerConfig) query() (*TemplateData, int, error) {
+	tplData := &TemplateData{
+		ID:   s.ID,
+		Host: s.Host,
+		Port: s.Port,
+	}
+
+	info, err := s.getInfo()
+	if err != nil {
+		return tplData, 0, err
+	}
+	tplData.Info = info
+
+	// Query the player list if enabled, failure here does not make the server offline
+	if s.QueryPlayers {
+		players, err := s.getPlayers()
+		if err != nil {
+			log.Warn().Err(err).Str("server", s.ID).Msg("Failed to retrieve players for server")
+		}
+		tplData.Players = players
+		tplData.playersQueried = err == nil
+	}
+
+	// Query the rules if enabled, Arma 3 and DayZ mods are decoded from the rules
+	if s.QueryRules {
+		rules, mods, err := s.getRules(info.ID)
+		if err != nil {
+			log.Warn().Err(err).Str("server", s.ID).Msg("Failed to retrieve rules for server")
+		}
+		tplData.Rules = rules
+		tplData.Mods = mods
+	}
+
+	var queue int
+	tplData.Extra, queue = parseExtra(info)
+
+	return tplData, queue, nil
+}
+
+// parseExtra parses extra keywords of Arma 3 and DayZ, returns them with the number of players in the queue
+func parseExtra(info *a2s.Info) (any, int) {
+	switch info.ID {
+	case appid.Arma3.Uint64():
+		return keywords.ParseArma3(info.Keywords), 0
+
+	case appid.DayZ.Uint64(), appid.DayZExp.Uint64():
+		dayzInfo := keywords.ParseDayZ(info.Keywords)
+		return dayzInfo, int(dayzInfo.PlayersQueue)
+	}
+
+	return nil, 0
+}
+
 /*
 getInfo queries the A2S server and returns the server information.
 
@@ -15,18 +84,136 @@ and retrieves the server information.
 Returns a pointer to a2s.Info containing server details and an error if the operation fails.
 */
 func (s *ServerConfig) getInfo() (*a2s.Info, error) {
-	client, err := a2s.New(s.Host, s.Port)
+	client, err := s.newClient()
+	if err != nil {
+		return nil, err
+	}
+	defer closeClient(client)
+
+	return client.GetInfo()
+}
+
+/*
+getPlayers queries the A2S server and returns the list of connected players.
+
+The challenge required by A2S_PLAYER is requested and resolved by the client itself.
+
+Returns a slice of a2s.Player and an error if the operation fails.
+*/
+func (s *ServerConfig) getPlayers() ([]a2s.Player, error) {
+	client, err := s.newClient()
 	if err != nil {
 		return nil, err
 	}
+	defer closeClient(client)
+
+	players, err := client.GetPlayers()
+	if err != nil {
+		return nil, err
+	}
+	if players == nil {
+		return nil, nil
+	}
+
+	return *players, nil
+}
+
+/*
+getRules queries the A2S server and returns the server rules.
+
+For Arma 3 and DayZ (detected by the game appID from A2S_INFO) the rules contain
+the Arma 3 Server Browser Protocol blob split over several rules, it is decoded
+and the list of mods is returned separately, the remaining rules are returned as is.
+
+Returns the rules map, the list of mods (only for Arma 3 and DayZ) and an error if the operation fails.
+*/
+func (s *ServerConfig) getRules(game uint64) (rules map[string]string, mods []a3sb.Mod, err error) {
+	// Malformed rules, e.g. with one byte keys, panic in the decoder of the server browser protocol
 	defer func() {
-		if err := client.Close(); err != nil {
-			log.Error().Err(err).Msg("Error close A2S client")
+		if r := recover(); r != nil {
+			rules, mods, err = nil, nil, fmt.Errorf("failed to decode rules: %v", r)
 		}
 	}()
 
+	client, err := s.newClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer closeClient(client)
+
+	if client.BufferSize < rulesBufferSize {
+		client.SetBufferSize(rulesBufferSize)
+	}
+
+	switch game {
+	case appid.Arma3.Uint64(), appid.DayZ.Uint64(), appid.DayZExp.Uint64():
+		decoded, err := (&a3sb.Client{Client: client}).GetRules(game)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return a3sbRulesMap(decoded), decoded.Mods, nil
+
+	default:
+		rules, err = client.GetRules()
+		return rules, nil, err
+	}
+}
+
+// a3sbRulesMap converts the decoded Arma 3/DayZ rules back to the flat map of rules
+func a3sbRulesMap(r *a3sb.Rules) map[string]string {
+	rules := make(map[string]string, len(r.ExtraRules)+8)
+	for k, v := range r.ExtraRules {
+		rules[k] = v
+	}
+
+	if r.Description != "" {
+		rules["description"] = r.Description
+	}
+	if r.Island != "" {
+		rules["island"] = r.Island
+	}
+	if r.Platform != "" {
+		rules["platform"] = r.Platform
+	}
+	if r.Language != 0 {
+		rules["language"] = r.Language.String()
+	}
+	if r.ClientPort != 0 {
+		rules["clientPort"] = strconv.FormatUint(uint64(r.ClientPort), 10)
+	}
+	if r.AllowedBuild != 0 {
+		rules["allowedBuild"] = strconv.FormatUint(uint64(r.AllowedBuild), 10)
+	}
+	if r.RequiredBuild != 0 {
+		rules["requiredBuild"] = strconv.FormatUint(uint64(r.RequiredBuild), 10)
+	}
+	if r.RequiredVersion != 0 {
+		rules["requiredVersion"] = strconv.FormatUint(uint64(r.RequiredVersion), 10)
+	}
+	if r.TimeLeft != 0 {
+		rules["timeLeft"] = strconv.FormatUint(uint64(r.TimeLeft), 10)
+	}
+
+	return rules
+}
+
+// newClient creates an A2S client with the buffer size and timeout from ServerConfig
+func (s *ServerConfig) newClient() (*a2s.Client, error) {
+	client, err := a2s.New(s.Host, s.Port)
+	if err != nil {
+		return nil, err
+	}
+
 	client.SetBufferSize(s.BufferSize)
 	client.SetDeadlineTimeout(s.Timeout)
 
-	return client.GetInfo()
+	return client, nil
+}
+
+// closeClient closes the A2S client and logs the error if any
+func closeClient(client *a2s.Client) {
+	if err := client.Close(); err != nil {
+		log.Error().Err(err).Msg("Error close A2S client")
+	}
 }
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"net"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/woozymasta/a2s/pkg/a2s"
+	"github.com/woozymasta/a2s/pkg/keywords"
+)
+
+// fakeServer answers A2S queries on a local UDP port with the configured responses
+type fakeServer struct {
+	info        *a2s.Info         // A2S_INFO response, no answer if nil
+	players     []a2s.Player      // A2S_PLAYER response
+	rules       map[string]string // A2S_RULES response
+	failPlayers atomic.Bool       // Answer A2S_PLAYER with a wrong response type
+}
+
+const fakeChallenge uint32 = 0x01020304
+
+// start listens on a local UDP port and returns the server configuration to query it
+func (f *fakeServer) start(t *testing.T) *ServerConfig {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	go func() {
+		buf := make([]byte, 1400)
+		for {
+			n, addr, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			if resp := f.response(buf[:n]); resp != nil {
+				_, _ = conn.WriteToUDP(resp, addr)
+			}
+		}
+	}()
+
+	return &ServerConfig{
+		ID:         "fake",
+		Host:       "127.0.0.1",
+		Port:       conn.LocalAddr().(*net.UDPAddr).Port,
+		Timeout:    1,
+		BufferSize: 1400,
+	}
+}
+
+// response builds the single-packet response to the request
+func (f *fakeServer) response(req []byte) []byte {
+	if len(req) < 5 {
+		return nil
+	}
+
+	resp := []byte{0xFF, 0xFF, 0xFF, 0xFF}
+	switch req[4] {
+	case 0x54:
+		if f.info == nil {
+			return nil
+		}
+		return append(resp, f.infoPayload()...)
+
+	case 0x55, 0x56:
+		if len(req) < 9 || binary.BigEndian.Uint32(req[5:9]) != fakeChallenge {
+			return binary.BigEndian.AppendUint32(append(resp, 0x41), fakeChallenge)
+		}
+		if req[4] == 0x56 {
+			return append(resp, f.rulesPayload()...)
+		}
+		if f.failPlayers.Load() {
+			return append(resp, 0x49, 0)
+		}
+		return append(resp, f.playersPayload()...)
+	}
+
+	return nil
+}
+
+func (f *fakeServer) infoPayload() []byte {
+	i := f.info
+	b := []byte{0x49, 17}
+	for _, s := range []string{i.Name, i.Map, "folder", "game"} {
+		b = append(append(b, s...), 0)
+	}
+	b = binary.LittleEndian.AppendUint16(b, uint16(min(i.ID, math.MaxUint16)))
+	b = append(b, i.Players, i.MaxPlayers, 0, 'd', 'l', 0, 0)
+	b = append(append(b, i.Version...), 0)
+
+	// Keywords and the 64-bit game ID for app IDs above uint16
+	b = append(b, 0x20|0x01)
+	b = append(append(b, strings.Join(i.Keywords, ",")...), 0)
+	return binary.LittleEndian.AppendUint64(b, i.ID)
+}
+
+func (f *fakeServer) playersPayload() []byte {
+	b := []byte{0x44, byte(len(f.players))}
+	for i, p := range f.players {
+		b = append(b, byte(i))
+		b = append(append(b, p.Name...), 0)
+		b = binary.LittleEndian.AppendUint32(b, p.Score)
+		b = binary.LittleEndian.AppendUint32(b, math.Float32bits(float32(p.Duration.Seconds())))
+	}
+	return b
+}
+
+func (f *fakeServer) rulesPayload() []byte {
+	b := binary.LittleEndian.AppendUint16([]byte{0x45}, uint16(len(f.rules)))
+	for k, v := range f.rules {
+		b = append(append(b, k...), 0)
+		b = append(append(b, v...), 0)
+	}
+	return b
+}
+
+func TestQueryPlayers(t *testing.T) {
+	f := &fakeServer{
+		info: &a2s.Info{Name: "Test", Map: "map", Players: 2, MaxPlayers: 10, ID: 107410},
+		players: []a2s.Player{
+			{Name: "alpha", Score: 5, Duration: time.Minute},
+			{Name: "beta", Score: 9, Duration: time.Hour},
+		},
+	}
+	srv := f.start(t)
+
+	// Players are not queried unless enabled
+	tpl, _, err := srv.query()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tpl.Info == nil || tpl.Info.Name != "Test" || tpl.Players != nil || tpl.playersQueried {
+		t.Errorf("data = %+v, expected info without players", tpl)
+	}
+
+	srv.QueryPlayers = true
+	if tpl, _, err = srv.query(); err != nil {
+		t.Fatal(err)
+	}
+	if len(tpl.Players) != 2 || tpl.Players[1].Name != "beta" || tpl.Players[1].Duration != time.Hour || !tpl.playersQueried {
+		t.Errorf("players = %+v", tpl.Players)
+	}
+
+	// Failed A2S_PLAYER does not make the server offline
+	f.failPlayers.Store(true)
+	if tpl, _, err = srv.query(); err != nil {
+		t.Fatal(err)
+	}
+	if tpl.Info == nil || tpl.Players != nil || tpl.playersQueried {
+		t.Errorf("data = %+v, expected online server without players", tpl)
+	}
+}
+
+func TestSortPlayers(t *testing.T) {
+	players := []a2s.Player{
+		{Name: "a", Score: 1, Duration: 3 * time.Minute},
+		{Name: "b", Score: 3, Duration: time.Minute},
+		{Name: "c", Score: 2, Duration: 2 * time.Minute},
+		{Name: "d", Score: 3, Duration: 4 * time.Minute},
+	}
+	names := func(list []a2s.Player) []string {
+		result := make([]string, len(list))
+		for i, p := range list {
+			result[i] = p.Name
+		}
+		return result
+	}
+
+	tests := []struct {
+		name string
+		got  []a2s.Player
+		want []string
+	}{
+		{"top", tplHelperTopPlayers(players, 0), []string{"b", "d", "c", "a"}},
+		{"top limited", tplHelperTopPlayers(players, 2), []string{"b", "d"}},
+		{"top limit from string", tplHelperTopPlayers(players, "1"), []string{"b"}},
+		{"longest", tplHelperLongestPlayers(players, 0), []string{"d", "a", "c", "b"}},
+		{"longest above length", tplHelperLongestPlayers(players, 10), []string{"d", "a", "c", "b"}},
+		{"empty", tplHelperTopPlayers(nil, 3), []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := names(tt.got); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: players = %q, expected %q", tt.name, got, tt.want)
+		}
+	}
+
+	if players[0].Name != "a" {
+		t.Error("source list is modified")
+	}
+}
+
+func TestQueryRules(t *testing.T) {
+	f := &fakeServer{
+		info:  &a2s.Info{Name: "Test", Map: "map", ID: 730},
+		rules: map[string]string{"sv_password": "0", "mp_timelimit": "30"},
+	}
+	srv := f.start(t)
+	srv.QueryRules = true
+
+	tpl, _, err := srv.query()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tpl.Rules) != 2 || tpl.Rules["mp_timelimit"] != "30" || tpl.Mods != nil {
+		t.Errorf("rules = %v, mods = %v", tpl.Rules, tpl.Mods)
+	}
+}
+
+func TestQueryRulesDayZ(t *testing.T) {
+	// Server browser protocol v2 blob: version, flags, DLC mask, one mod, no signatures and the description
+	blob := []byte{2, 0, 0, 0, 1}
+	blob = binary.LittleEndian.AppendUint32(blob, 0xAABBCCDD)
+	blob = binary.LittleEndian.AppendUint32(append(blob, 4), 1559212036)
+	blob = append(append(blob, byte(len("CF"))), "CF"...)
+	blob = append(append(blob, 0, byte(len("Hello"))), "Hello"...)
+
+	f := &fakeServer{
+		info: &a2s.Info{Name: "DayZ", Map: "chernarusplus", ID: 221100, Keywords: []string{"battleye", "lqs3", "etm4.000000"}},
+		rules: map[string]string{
+			"\x01\x01":     string(escapeA3SB(blob)),
+			"island":       "chernarusplus",
+			"allowedBuild": "1",
+			"customRule":   "value",
+		},
+	}
+	srv := f.start(t)
+	srv.QueryRules = true
+
+	tpl, queue, err := srv.query()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tpl.Mods) != 1 || tpl.Mods[0].Name != "CF" || tpl.Mods[0].ID != 1559212036 || tpl.Mods[0].Hash != 0xAABBCCDD {
+		t.Errorf("mods = %+v", tpl.Mods)
+	}
+	for k, v := range map[string]string{"description": "Hello", "island": "chernarusplus", "allowedBuild": "1", "customRule": "value"} {
+		if tpl.Rules[k] != v {
+			t.Errorf("rule %s = %q, expected %q", k, tpl.Rules[k], v)
+		}
+	}
+	if queue != 3 {
+		t.Errorf("queue = %d, expected 3", queue)
+	}
+}
+
+func TestParseExtra(t *testing.T) {
+	extra, queue := parseExtra(&a2s.Info{ID: 221100, Keywords: []string{"lqs5", "shardUS1"}})
+	if _, ok := extra.(*keywords.DayZ); !ok || queue != 5 {
+		t.Errorf("DayZ extra = %T, queue = %d", extra, queue)
+	}
+
+	if extra, _ = parseExtra(&a2s.Info{ID: 107410, Keywords: []string{"bf"}}); extra == nil {
+		t.Error("Arma 3 keywords are not parsed")
+	}
+
+	if extra, queue = parseExtra(&a2s.Info{ID: 730, Keywords: []string{"lqs5"}}); extra != nil || queue != 0 {
+		t.Errorf("other game extra = %v, queue = %d", extra, queue)
+	}
+}
+
+// escapeA3SB escapes the server browser protocol bytes the way they are sent in rule values
+func escapeA3SB(data []byte) []byte {
+	var b []byte
+	for _, c := range data {
+		switch c {
+		case 0x00:
+			b = append(b, 0x01, 0x02)
+		case 0x01:
+			b = append(b, 0x01, 0x01)
+		case 0xFF:
+			b = append(b, 0x01, 0x03)
+		default:
+			b = append(b, c)
+		}
+	}
+	return b
+}
+
+func TestQueryRulesMalformed(t *testing.T) {
+	// One byte rule key panics in the decoder of the server browser protocol
+	f := &fakeServer{
+		info:  &a2s.Info{Name: "DayZ", Map: "chernarusplus", ID: 221100},
+		rules: map[string]string{"x": "1"},
+	}
+	srv := f.start(t)
+	srv.QueryRules = true
+
+	tpl, _, err := srv.query()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tpl.Info == nil || tpl.Rules != nil || tpl.Mods != nil {
+		t.Errorf("data = %+v, expected online server without rules", tpl)
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	maxMessageContent = 2000 // Maximum length of Discord message content
+
+	defaultOfflineMessage = "🔴 Server **{{ .ID }}** is offline"
+	defaultOnlineMessage  = "🟢 Server **{{ .ID }}** is back online after {{ .Outage }}"
+)
+
+/*
+Alerts represents the configuration of online/offline transition alerts.
+
+Alerts are posted to the text channel when a server goes offline after
+the configured number of consecutive failed queries, and when it is back online.
+*/
+type Alerts struct {
+	ChannelID      string `yaml:"channel_id,omitempty"`           // Discord text channel ID for alerts
+	RoleID         string `yaml:"role_id,omitempty"`              // Discord role ID to mention in alerts
+	OfflineMessage string `yaml:"offline_message,omitempty"`      // Template for the offline alert
+	OnlineMessage  string `yaml:"online_message,omitempty"`       // Template for the online alert
+	Failures       int    `yaml:"failures,omitempty" default:"3"` // Consecutive failures before declaring an outage
+
+	OfflineMessageFile string `yaml:"offline_message_file,omitempty"` // File with template for the offline alert
+	OnlineMessageFile  string `yaml:"online_message_file,omitempty"`  // File with template for the online alert
+
+	tplOffline *template.Template // Compiled OfflineMessage or the default one
+	tplOnline  *template.Template // Compiled OnlineMessage or the default one
+}
+
+/*
+AlertData represents the data passed to alert templates.
+
+It includes all fields of the server TemplateData and the outage details.
+*/
+type AlertData struct {
+	*TemplateData
+	Since  time.Time     // Time of the first failed query of the outage
+	Outage time.Duration // Outage duration, zero for offline alerts
+}
+
+// serverState is the last known state of the server used for transition alerts
+type serverState int
+
+const (
+	stateUnknown serverState = iota // No conclusive result since start
+	stateOnline                     // Server responded
+	stateOffline                    // Server failed the configured number of queries in a row
+)
+
+// String returns the name of the state
+func (s serverState) String() string {
+	switch s {
+	case stateOnline:
+		return "online"
+	case stateOffline:
+		return "offline"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText encodes the state as its name
+func (s serverState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes the state from its name, unknown names are decoded as stateUnknown
+func (s *serverState) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "online":
+		*s = stateOnline
+	case "offline":
+		*s = stateOffline
+	default:
+		*s = stateUnknown
+	}
+
+	return nil
+}
+
+// trackState updates the state of the server after a query and posts an alert on transitions
+func (s *ServerConfig) trackState(ds *discordgo.Session, cfg *Config, tpl *TemplateData) {
+	defer botState.setServerStatus(s)
+
+	if tmpl, data := s.transition(cfg, tpl, time.Now()); tmpl != nil {
+		go cfg.Alerts.send(ds, s.alertsChannel(cfg), tmpl, data)
+	}
+}
+
+/*
+transition updates the state of the server after a query and returns the alert template and data
+to send, nil template if no alert is due.
+
+The initial state after start is detected silently, only real transitions are alerted.
+The online alert is sent only if the offline alert of the outage was sent.
+*/
+func (s *ServerConfig) transition(cfg *Config, tpl *TemplateData, now time.Time) (*template.Template, *AlertData) {
+	if tpl.Info == nil {
+		if s.failures == 0 {
+			s.failedSince = now
+		}
+		s.failures++
+
+		if s.failures < cfg.Alerts.Failures || s.state == stateOffline {
+			return nil, nil
+		}
+
+		prev := s.state
+		s.state = stateOffline
+		log.Warn().Str("server", s.ID).Int("failures", s.failures).Msg("Server is offline")
+
+		if prev != stateOnline {
+			return nil, nil
+		}
+		s.alerted = true
+		return cfg.Alerts.tplOffline, &AlertData{TemplateData: tpl, Since: s.failedSince}
+	}
+
+	prev, alerted := s.state, s.alerted
+	since := s.failedSince
+	s.state = stateOnline
+	s.failures = 0
+	s.alerted = false
+
+	if prev != stateOffline {
+		return nil, nil
+	}
+
+	outage := now.Sub(since).Truncate(time.Second)
+	log.Info().Str("server", s.ID).Dur("outage", outage).Msg("Server is back online")
+
+	// Servers offline since start were not alerted, so their recovery is not alerted too
+	if !alerted {
+		return nil, nil
+	}
+	return cfg.Alerts.tplOnline, &AlertData{TemplateData: tpl, Since: since, Outage: outage}
+}
+
+// alertsChannel returns the alerts channel of the server or the global one
+func (s *ServerConfig) alertsChannel(cfg *Config) string {
+	if s.AlertsChanID != "" {
+		return s.AlertsChanID
+	}
+
+	return cfg.Alerts.ChannelID
+}
+
+// send renders the alert template and posts it to the channel with an optional role mention
+func (a *Alerts) send(ds *discordgo.Session, channelID string, tmpl *template.Template, data *AlertData) {
+	if channelID == "" || ds == nil {
+		return
+	}
+
+	content, err := executeTemplate(tmpl, data)
+	if err != nil {
+		log.Error().Err(err).Str("server", data.ID).Msg("Error rendering alert template")
+	}
+
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return
+	}
+
+	msg := &discordgo.MessageSend{
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	}
+	if a.RoleID != "" {
+		content = "<@&" + a.RoleID + "> " + content
+		msg.AllowedMentions.Roles = []string{a.RoleID}
+	}
+	msg.Content = truncate(content, maxMessageContent)
+
+	ctx, cancel := context.WithTimeout(context.Background(), discordTimeout)
+	defer cancel()
+
+	if _, err := ds.ChannelMessageSendComplex(channelID, msg, discordgo.WithContext(ctx)); err != nil {
+		log.Error().Err(err).Str("server", data.ID).Str("channel", channelID).Msg("Failed to send alert")
+		return
+	}
+
+	log.Debug().Str("server", data.ID).Str("channel", channelID).Msg("Alert sent")
+}
+
+// orDefault returns the value or the default if the value is empty
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+
+	return value
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/woozymasta/a2s/pkg/a2s"
+)
+
+func TestTransition(t *testing.T) {
+	cfg := &Config{Alerts: Alerts{Failures: 2}}
+	if err := cfg.compileTemplates(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Steps are query results, "+" online and "-" failed, with the expected alert and state after it
+	type step struct {
+		up    bool
+		alert string // Text in the rendered alert, empty if no alert is expected
+		state serverState
+	}
+	on := func(alert string) step { return step{up: true, alert: alert, state: stateOnline} }
+	off := func(alert string, state serverState) step { return step{alert: alert, state: state} }
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name:  "online since start",
+			steps: []step{on(""), on("")},
+		},
+		{
+			name:  "outage",
+			steps: []step{on(""), off("", stateOnline), off("is offline", stateOffline), off("", stateOffline), on("back online after 3m0s")},
+		},
+		{
+			name:  "failures below the threshold",
+			steps: []step{on(""), off("", stateOnline), on(""), off("", stateOnline), on("")},
+		},
+		{
+			name:  "offline since start",
+			steps: []step{off("", stateUnknown), off("", stateOffline), on(""), off("", stateOnline), off("is offline", stateOffline)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &ServerConfig{ID: "srv"}
+			now := time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+			for i, st := range tt.steps {
+				tpl := &TemplateData{ID: srv.ID}
+				if st.up {
+					tpl.Info = &a2s.Info{}
+				}
+
+				tmpl, data := srv.transition(cfg, tpl, now)
+				now = now.Add(time.Minute)
+
+				var alert string
+				if tmpl != nil {
+					var err error
+					if alert, err = executeTemplate(tmpl, data); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				if (st.alert == "") != (alert == "") || !strings.Contains(alert, st.alert) {
+					t.Errorf("step %d: alert = %q, expected %q", i, alert, st.alert)
+				}
+				if srv.state != st.state {
+					t.Errorf("step %d: state = %s, expected %s", i, srv.state, st.state)
+				}
+			}
+		})
+	}
+}
+
+func TestTransitionRestoredState(t *testing.T) {
+	cfg := &Config{Alerts: Alerts{Failures: 1}}
+	if err := cfg.compileTemplates(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Offline state restored from the state file without a sent alert is recovered silently
+	srv := &ServerConfig{ID: "srv", state: stateOffline}
+	if tmpl, _ := srv.transition(cfg, &TemplateData{Info: &a2s.Info{}}, time.Now()); tmpl != nil {
+		t.Error("recovery alerted without a sent offline alert")
+	}
+
+	// With the sent alert restored, the recovery is alerted
+	srv = &ServerConfig{ID: "srv", state: stateOffline, alerted: true, failedSince: time.Now().Add(-time.Hour)}
+	if tmpl, data := srv.transition(cfg, &TemplateData{Info: &a2s.Info{}}, time.Now()); tmpl != cfg.Alerts.tplOnline || data.Outage < time.Hour {
+		t.Errorf("recovery alert = %v, %+v", tmpl, data)
+	}
+}
+
+func TestServerStateText(t *testing.T) {
+	for _, s := range []serverState{stateUnknown, stateOnline, stateOffline} {
+		text, err := s.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got serverState
+		if err := got.UnmarshalText(text); err != nil || got != s {
+			t.Errorf("state %s decoded as %s, %v", s, got, err)
+		}
+	}
+
+	var s serverState = stateOnline
+	_ = s.UnmarshalText([]byte("broken"))
+	if s != stateUnknown {
+		t.Errorf("unknown name decoded as %s", s)
+	}
+}
@@ -28,6 +28,14 @@ func parseArgs() {
 	case "--example", "-e":
 		fmt.Println(exampleConfig)
 		os.Exit(0)
+	case "--get-env", "-g":
+		printEnv()
+	case "--validate", "-t":
+		runValidate(os.Args[2:])
+	case "--render", "-r":
+		runRender(os.Args[2:])
+	case "--query", "-q":
+		runQuery(os.Args[2:])
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown command. Use --help for a list of available commands.")
 		os.Exit(0)
@@ -42,9 +50,22 @@ Is a Discord bot that monitors game servers using A2S queries and updates Discor
 
 Usage:
   %[1]s [option] [config.(yaml|json)]
+  %[1]s --validate [--discord] [config.(yaml|json)]
+  %[1]s --render <server-id> [--fixture file.json] [--save file.json] [config.(yaml|json)]
+  %[1]s --query <host:port> [--players] [--rules] [--json] [--timeout 3] [--buffer 1024]
 
 Available options:
   -e, --example    Prints an example YAML configuration file.
+  -g, --get-env    Prints all supported environment variables with default values.
+  -t, --validate   Checks the configuration and templates, with --discord (-d) also
+                   logs in to check channels and permissions of the bot.
+  -r, --render     Renders all templates of the server from a live query or a JSON
+                   fixture (--fixture, -f), the query result can be saved as
+                   fixture with --save (-s).
+  -q, --query      Queries the server by address and prints A2S_INFO, parsed keywords
+                   and latency, with --players (-p) and --rules (-r) also A2S_PLAYER
+                   and A2S_RULES, as table or JSON with --json (-j). Timeout in
+                   seconds and buffer size are set by --timeout (-t) and --buffer (-b).
   -v, --version    Show version, commit, and build time.
   -h, --help       Prints this help message.
 
@@ -52,9 +73,21 @@ Examples:
   Save an example YAML configuration to file:
     %[1]s -e > config.yaml
 
-  Print an example environment variables:
+  Save an example YAML configuration with merged anchors:
     %[1]s -e | yq 'explode(.) | del(.base-template)' > config.yaml
 
+  Print supported environment variables:
+    %[1]s --get-env > .env
+
+  Check the configuration, templates and Discord channels:
+    %[1]s --validate --discord config.yaml
+
+  Preview templates of the server and save the query result as fixture:
+    %[1]s --render cherno --save cherno.json config.yaml
+
+  Query the server with players and rules as JSON:
+    %[1]s --query 127.0.0.1:27016 --players --rules --json
+
 `, filepath.Base(os.Args[0]), vars.Version)
 	os.Exit(0)
 }
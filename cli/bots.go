@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+/*
+BotConfig represents the main bot.
+
+The bot key accepts a single bot or a list of bots, the first item of the list
+is the main bot and the rest are additional bots added before the bots list.
+*/
+type BotConfig struct {
+	Token          string        `yaml:"token"`                         // Discord bot token
+	UpdateInterval time.Duration `yaml:"update_interval" default:"30s"` // Interval for status updates
+	Concurrency    int           `yaml:"concurrency" default:"10"`      // Number of concurrent operations
+	StateFile      string        `yaml:"state_file,omitempty"`          // Path to the file to persist state between restarts
+	NoCommands     bool          `yaml:"no_commands,omitempty"`         // Do not register slash commands
+	Servers        []string      `yaml:"servers,omitempty"`             // IDs of servers shown in the presence, all if empty
+	Presence       Presence      `yaml:"presence,omitempty"`            // Rich Presence configuration
+
+	bots []BotIdentity // Additional bots from the list form of the bot key
+}
+
+/*
+BotIdentity represents an additional Discord bot bound to a subset of servers.
+
+Communities often run one bot per game server, so every member list entry shows
+the presence of its own server. The bot shows the presence of its servers, edits
+their channels and categories, posts their status messages and sends their alerts.
+Reports, player events, the status message of all servers and slash commands are
+handled by the main bot. Servers are queried once and the results are shared
+between all bots.
+*/
+type BotIdentity struct {
+	Token    string   `yaml:"token"`              // Discord bot token
+	Servers  []string `yaml:"servers"`            // IDs of servers bound to the bot
+	Presence Presence `yaml:"presence,omitempty"` // Rich Presence configuration
+}
+
+// botIdentityKeys are the keys allowed in additional bots of the bot list, other options are process-wide
+var botIdentityKeys = []string{"token", "servers", "presence"}
+
+// UnmarshalYAML decodes a single bot or a list of bots with the main bot first
+func (b *BotConfig) UnmarshalYAML(node *yaml.Node) error {
+	type plain BotConfig
+	if node.Kind != yaml.SequenceNode {
+		return node.Decode((*plain)(b))
+	}
+
+	if len(node.Content) == 0 {
+		return fmt.Errorf("line %d: bot list is empty", node.Line)
+	}
+	if err := node.Content[0].Decode((*plain)(b)); err != nil {
+		return err
+	}
+
+	b.bots = make([]BotIdentity, len(node.Content)-1)
+	for i, item := range node.Content[1:] {
+		if item.Kind == yaml.MappingNode {
+			for j := 0; j < len(item.Content); j += 2 {
+				if key := item.Content[j].Value; !slices.Contains(botIdentityKeys, key) {
+					return fmt.Errorf("line %d: option %q is allowed only for the first bot", item.Content[j].Line, key)
+				}
+			}
+		}
+		if err := item.Decode(&b.bots[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// presenceBot is a Discord session showing the Rich Presence of a subset of servers
+type presenceBot struct {
+	ds      *discordgo.Session
+	name    string // Name of the bot for logs
+	index   int    // Index in Config.Bots, -1 for the main bot
+	sender  PresenceSender
+	rotator PresenceRotator
+}
+
+// presenceBots are the main bot and all additional bots, set once at startup
+var presenceBots []*presenceBot
+
+// newPresenceBot creates the presence of the session bound to the bot configuration by index
+func newPresenceBot(ds *discordgo.Session, index int) *presenceBot {
+	name := "main"
+	if index >= 0 {
+		name = "bots." + strconv.Itoa(index)
+	}
+
+	return &presenceBot{ds: ds, name: name, index: index}
+}
+
+/*
+openBots opens Discord sessions of all additional bots and waits for their Ready events.
+
+Presence can be set only after Ready, so the sessions are returned ready to use.
+Sessions which were opened before an error are returned too, so they can be closed.
+*/
+func openBots(cfg *Config) ([]*discordgo.Session, error) {
+	sessions := make([]*discordgo.Session, 0, len(cfg.Bots))
+
+	for i, bot := range cfg.Bots {
+		ds, err := discordgo.New("Bot " + bot.Token)
+		if err != nil {
+			return sessions, fmt.Errorf("failed to create session of bots #%d: %w", i, err)
+		}
+
+		ready := make(chan struct{})
+		ds.AddHandlerOnce(func(_ *discordgo.Session, _ *discordgo.Ready) { close(ready) })
+
+		if err := ds.Open(); err != nil {
+			return sessions, fmt.Errorf("failed to open session of bots #%d: %w", i, err)
+		}
+		sessions = append(sessions, ds)
+
+		select {
+		case <-ready:
+		case <-time.After(discordTimeout):
+			return sessions, fmt.Errorf("session of bots #%d received no Ready event within %s", i, discordTimeout)
+		}
+
+		log.Info().Int("bot", i).Strs("servers", bot.Servers).Msg("Additional bot connected to Discord")
+	}
+
+	return sessions, nil
+}
+
+/*
+botSession returns the session of the additional bot bound to the server, the main session otherwise.
+
+Additional bots are kept by index on reload, so the sessions match the configuration.
+*/
+func botSession(ds *discordgo.Session, cfg *Config, id string) *discordgo.Session {
+	if cfg == nil {
+		return ds
+	}
+
+	for _, b := range presenceBots {
+		if b.index >= 0 && b.index < len(cfg.Bots) && slices.Contains(cfg.Bots[b.index].Servers, id) {
+			return b.ds
+		}
+	}
+
+	return ds
+}
+
+// config returns the presence configuration and server IDs of the bot, empty IDs mean all servers
+func (b *presenceBot) config(cfg *Config) (*Presence, []string) {
+	if b.index < 0 || b.index >= len(cfg.Bots) {
+		return &cfg.Bot.Presence, cfg.Bot.Servers
+	}
+
+	return &cfg.Bots[b.index].Presence, cfg.Bots[b.index].Servers
+}
+
+/*
+update sets the Rich Presence of the bot from the results of its servers.
+
+If rotation is configured, the presence is driven by the rotator on its own
+interval, only the first presence is set here right after the first query.
+*/
+func (b *presenceBot) update(cfg *Config, data *SummaryData) error {
+	p, servers := b.config(cfg)
+	data = data.subset(servers)
+
+	if len(p.Rotate) > 0 {
+		if b.rotator.started() {
+			return nil
+		}
+		return b.rotator.next(b, p, data)
+	}
+
+	return b.sender.set(b.ds, p.makeUSD(data))
+}
+
+// subset returns the data of the servers with the IDs in configuration order, all servers if IDs are empty
+func (d *SummaryData) subset(ids []string) *SummaryData {
+	if len(ids) == 0 {
+		return d
+	}
+
+	servers := make([]*TemplateData, 0, len(ids))
+	for _, tpl := range d.Servers {
+		if slices.Contains(ids, tpl.ID) {
+			servers = append(servers, tpl)
+		}
+	}
+
+	return &SummaryData{Stats: statsOf(servers), Servers: servers}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/woozymasta/a2s/pkg/a2s"
+)
+
+func TestBotList(t *testing.T) {
+	useConfig(t, `
+bot:
+  - token: main
+    update_interval: 1m
+  - token: cherno
+    servers: [cherno]
+    presence:
+      online: cherno
+bots:
+  - token: livonia
+    servers: [livonia]
+servers:
+  - id: cherno
+  - id: livonia
+`)
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Bot.Token != "main" || cfg.Bot.UpdateInterval != time.Minute || cfg.Bot.Concurrency != 10 {
+		t.Errorf("main bot = %+v", cfg.Bot)
+	}
+
+	tokens := make([]string, len(cfg.Bots))
+	for i, bot := range cfg.Bots {
+		tokens[i] = bot.Token
+	}
+	if !slices.Equal(tokens, []string{"cherno", "livonia"}) || cfg.Bots[0].Presence.Online != "cherno" {
+		t.Errorf("additional bots = %+v", cfg.Bots)
+	}
+}
+
+func TestBotListErrors(t *testing.T) {
+	tests := map[string]string{
+		"empty list":            "bot: []\nservers: []\n",
+		"process-wide option":   "bot:\n  - token: main\n  - token: other\n    servers: [a]\n    state_file: state.json\nservers:\n  - id: a\n",
+		"server bound twice":    "bot:\n  - token: main\n  - token: b1\n    servers: [a]\n  - token: b2\n    servers: [a]\nservers:\n  - id: a\n",
+		"duplicate token":       "bot:\n  - token: main\n  - token: main\n    servers: [a]\nservers:\n  - id: a\n",
+		"additional no servers": "bot:\n  - token: main\n  - token: other\nservers:\n  - id: a\n",
+	}
+
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			useConfig(t, content)
+			if _, err := parseConfig(); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestReloadKeptBots(t *testing.T) {
+	useConfig(t, "bot:\n  token: main\nbots:\n  - token: a\n    servers: [a]\nservers:\n  - id: a\n  - id: b\n")
+	cur, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Changed token keeps the current bots, they must still match the new servers
+	useConfig(t, "bot:\n  token: main\nbots:\n  - token: new\n    servers: [b]\nservers:\n  - id: a\n  - id: b\n")
+	cfg, err := reloadConfig(cur)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Bots[0].Token != "a" || !slices.Equal(cfg.Bots[0].Servers, []string{"a"}) {
+		t.Errorf("bots = %+v, expected the current bots", cfg.Bots)
+	}
+
+	useConfig(t, "bot:\n  token: main\nbots:\n  - token: new\n    servers: [b]\nservers:\n  - id: b\n")
+	if _, err := reloadConfig(cfg); err == nil || !strings.Contains(err.Error(), `"a"`) {
+		t.Errorf("error = %v, expected the kept bot to refer to removed server", err)
+	}
+}
+
+func TestBotSession(t *testing.T) {
+	mainDS, cherno := &discordgo.Session{}, &discordgo.Session{}
+	cfg := &Config{Bots: []BotIdentity{{Token: "cherno", Servers: []string{"cherno"}}}}
+
+	prev := presenceBots
+	presenceBots = []*presenceBot{newPresenceBot(mainDS, -1), newPresenceBot(cherno, 0)}
+	t.Cleanup(func() { presenceBots = prev })
+
+	if ds := botSession(mainDS, cfg, "cherno"); ds != cherno {
+		t.Error("bound server uses the main session")
+	}
+	if ds := botSession(mainDS, cfg, "livonia"); ds != mainDS {
+		t.Error("unbound server uses an additional session")
+	}
+	if ds := botSession(mainDS, nil, "cherno"); ds != mainDS {
+		t.Error("session without configuration is not the main one")
+	}
+}
+
+func TestSummarySubset(t *testing.T) {
+	servers := []*TemplateData{
+		{ID: "a", Info: &a2s.Info{Players: 1, MaxPlayers: 10}},
+		{ID: "b", Info: &a2s.Info{Players: 2, MaxPlayers: 20}},
+		{ID: "c"},
+	}
+	data := &SummaryData{Stats: statsOf(servers), Servers: servers}
+
+	if got := data.subset(nil); got != data {
+		t.Error("empty IDs do not return all servers")
+	}
+
+	got := data.subset([]string{"c", "b"})
+	if len(got.Servers) != 2 || got.Servers[0].ID != "b" || got.Stats.Players != 2 || got.Stats.OnlineServers != 1 {
+		t.Errorf("subset = %+v %+v, expected servers b and c in configuration order", got.Stats, got.Servers)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+CachedData is the latest TemplateData collected for a server
+together with the time when it was collected.
+*/
+type CachedData struct {
+	Tpl       *TemplateData // Latest template data
+	UpdatedAt time.Time     // Time of the query
+}
+
+/*
+DataCache stores the latest query results for every server by server ID.
+
+It allows to answer Discord interactions without querying the game servers again.
+*/
+type DataCache struct {
+	data map[string]CachedData
+	all  *SummaryData // Latest results of all servers from the last update
+	mu   sync.RWMutex
+}
+
+// dataCache holds the latest query results shared between update() and interaction handlers
+var dataCache = &DataCache{data: make(map[string]CachedData)}
+
+// set stores the template data for the server
+func (c *DataCache) set(tpl *TemplateData) {
+	if tpl == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data[tpl.ID] = CachedData{Tpl: tpl, UpdatedAt: time.Now()}
+}
+
+// get returns the cached template data for the server ID
+func (c *DataCache) get(id string) (CachedData, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	data, ok := c.data[id]
+	return data, ok
+}
+
+// delete removes the cached template data of the server
+func (c *DataCache) delete(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.data, id)
+}
+
+// setSummary stores the latest results of all servers
+func (c *DataCache) setSummary(data *SummaryData) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.all = data
+}
+
+// summary returns the latest results of all servers, nil before the first update
+func (c *DataCache) summary() *SummaryData {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.all
+}
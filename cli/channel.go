@@ -3,26 +3,45 @@
 package main
 
 import (
-	"context"
+	"unicode/utf8"
 
-	"github.com/bwmarrin/discordgo"
 	"github.com/rs/zerolog/log"
 	"github.com/zeebo/xxh3"
 )
 
-// updateChannel attempts to render the channel's template, compare hash, edit if needed
-func (s *ServerConfig) updateChannel(ctx context.Context, ds *discordgo.Session, tpl *TemplateData) error {
-	if s.ChannelID == "" || ds == nil || tpl == nil {
-		return nil
+// Discord limits of channel names and topics
+const (
+	maxChannelName  = 100
+	maxChannelTopic = 1024
+)
+
+// truncate cuts the text longer than limit bytes at a rune boundary and ends it with "..."
+func truncate(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+
+	cut := max(limit-3, 0)
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+
+	return s[:cut] + "..."
+}
+
+// updateChannel attempts to render the channel's template and schedule the edit
+func (s *ServerConfig) updateChannel(tpl *TemplateData) {
+	if s.ChannelID == "" || tpl == nil {
+		return
 	}
 	if s.ChannelName == "" && s.ChannelDesc == "" {
-		return nil
+		return
 	}
 
 	// Render templates
 	var name, description string
 	if s.ChannelName != "" {
-		rendered, err := tpl.render(s.ChannelName)
+		rendered, err := tpl.render(s.tplChannelName)
 		if err != nil {
 			log.Error().Err(err).Str("channel", s.ChannelID).Msg("Error rendering channel name template")
 		}
@@ -30,71 +49,39 @@ func (s *ServerConfig) updateChannel(ctx context.Context, ds *discordgo.Session,
 	}
 
 	if s.ChannelDesc != "" {
-		rendered, err := tpl.render(s.ChannelDesc)
+		rendered, err := tpl.render(s.tplChannelDesc)
 		if err != nil {
 			log.Error().Err(err).Str("channel", s.ChannelID).Msg("Error rendering channel description template")
 		} else {
-			description = rendered
-
-			if len(description) > 1024 {
-				description = description[:1021] + "..."
-			}
+			description = truncate(rendered, maxChannelTopic)
 		}
 	}
 
-	// Compare hashes
-	newHash := xxh3.HashString(name + description)
-	if newHash == s.prevChannelHash {
-		log.Debug().Str("channel", s.ChannelID).Msg("Skipping update for channel without changes detected")
-		return nil
-	}
-
-	log.Debug().
-		Str("channel", s.ChannelID).
-		Uint64("new hash", newHash).
-		Uint64("prev hash", s.prevChannelHash).
-		Msgf("Updating channel")
-
-	// Actual edit (context-based)
-	err := editChannel(ctx, ds, s.ChannelID, name, description)
-	if err != nil {
-		return err
-	}
-
-	// If successful
-	s.prevChannelHash = newHash
-	return nil
+	// Schedule the edit, the scheduler compares hashes and respects rate limits
+	channelScheduler.submit(s.ChannelID, &channelEdit{
+		server:      s.ID,
+		name:        name,
+		description: description,
+		hash:        xxh3.HashString(name + description),
+	})
 }
 
-// updateCategory attempts to render the category's template, compare hash, edit if needed
-func (s *ServerConfig) updateCategory(ctx context.Context, ds *discordgo.Session, tpl *TemplateData) error {
-	if s.CategoryID == "" || s.CategoryName == "" || ds == nil || tpl == nil {
-		return nil
+// updateCategory attempts to render the category's template and schedule the edit
+func (s *ServerConfig) updateCategory(tpl *TemplateData) {
+	if s.CategoryID == "" || s.CategoryName == "" || tpl == nil {
+		return
 	}
 
-	name, err := tpl.render(s.CategoryName)
+	name, err := tpl.render(s.tplCategoryName)
 	if err != nil {
 		log.Error().Err(err).Str("channel", s.CategoryID).Msg("Error rendering category name template")
 		name = ""
 	}
 
-	newHash := xxh3.HashString(name)
-	if newHash == s.prevCategoryHash {
-		log.Debug().Str("channel", s.CategoryID).Msg("Skipping update for category without changes detected")
-		return nil
-	}
-
-	log.Debug().
-		Str("category", s.ChannelID).
-		Uint64("new hash", newHash).
-		Uint64("prev hash", s.prevCategoryHash).
-		Msgf("Updating category")
-
-	err = editChannel(ctx, ds, s.CategoryID, name, "")
-	if err != nil {
-		return err
-	}
-	s.prevCategoryHash = newHash
-
-	return nil
+	channelScheduler.submit(s.CategoryID, &channelEdit{
+		server:   s.ID,
+		name:     name,
+		hash:     xxh3.HashString(name),
+		category: true,
+	})
 }
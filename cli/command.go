@@ -0,0 +1,387 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+	"github.com/woozymasta/a2s/pkg/keywords"
+	"github.com/woozymasta/steam/utils/appid"
+)
+
+const (
+	colorOnline  = 0x2ECC71 // Embed color for online servers
+	colorOffline = 0xE74C3C // Embed color for offline servers
+	colorPartial = 0xF1C40F // Embed color when only part of servers is online
+
+	maxEmbedFields  = 25 // Discord limit of fields in one embed
+	maxChoices      = 25 // Discord limit of autocomplete choices
+	maxChoiceLength = 100
+)
+
+// applicationCommands is the list of slash commands registered by the bot
+var applicationCommands = []*discordgo.ApplicationCommand{
+	{
+		Name:        "status",
+		Description: "Show the current status of the game servers",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         "server",
+				Description:  "Server ID",
+				Autocomplete: true,
+			},
+		},
+	},
+	{
+		Name:        "graph",
+		Description: "Show the graph of players of the game servers",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         "server",
+				Description:  "Server ID, all servers if empty",
+				Autocomplete: true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "range",
+				Description: "Time range, 24h if empty",
+				Choices:     graphRangeChoices(),
+			},
+		},
+	},
+	{
+		Name:        "uptime",
+		Description: "Show the uptime of the game servers for 24h, 7d and 30d",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         "server",
+				Description:  "Server ID, all servers if empty",
+				Autocomplete: true,
+			},
+		},
+	},
+}
+
+/*
+registerCommands overwrites the global application commands of the bot.
+
+It is called on the Ready event, so the commands always match the current version of the bot.
+*/
+func registerCommands(ds *discordgo.Session) error {
+	if ds.State == nil || ds.State.User == nil {
+		return fmt.Errorf("session has no user, Ready event not received")
+	}
+
+	_, err := ds.ApplicationCommandBulkOverwrite(ds.State.User.ID, "", applicationCommands)
+	if err != nil {
+		return fmt.Errorf("failed to register application commands: %w", err)
+	}
+
+	log.Debug().Int("count", len(applicationCommands)).Msg("Application commands registered")
+	return nil
+}
+
+/*
+handleInteraction dispatches application command and autocomplete interactions.
+
+All answers are built from the latest cached query results, game servers are never queried here.
+*/
+func handleInteraction(ds *discordgo.Session, i *discordgo.InteractionCreate, cfg *Config) {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		data := i.ApplicationCommandData()
+		switch data.Name {
+		case "status":
+			respondEmbeds(ds, i, cfg.statusEmbeds(optionString(data.Options, "server")))
+		case "graph":
+			respondGraph(ds, i, cfg, optionString(data.Options, "server"), optionString(data.Options, "range"))
+		case "uptime":
+			respondEmbeds(ds, i, []*discordgo.MessageEmbed{cfg.uptimeEmbed(optionString(data.Options, "server"))})
+		}
+
+	case discordgo.InteractionApplicationCommandAutocomplete:
+		data := i.ApplicationCommandData()
+		for _, opt := range data.Options {
+			if opt.Focused && opt.Name == "server" {
+				respondChoices(ds, i, cfg.serverChoices(opt.StringValue()))
+			}
+		}
+	}
+}
+
+// statusEmbeds builds the answer for /status, a single server embed or the summary of all servers
+func (c *Config) statusEmbeds(id string) []*discordgo.MessageEmbed {
+	if id == "" && len(c.Servers) == 1 {
+		id = c.Servers[0].ID
+	}
+
+	if id != "" {
+		for _, srv := range c.Servers {
+			if srv.ID != id {
+				continue
+			}
+
+			cached, ok := dataCache.get(id)
+			if !ok {
+				return []*discordgo.MessageEmbed{messageEmbed(fmt.Sprintf("No data for server %s yet", id))}
+			}
+
+			return []*discordgo.MessageEmbed{cached.Tpl.statusEmbed(cached.UpdatedAt)}
+		}
+
+		return []*discordgo.MessageEmbed{messageEmbed(fmt.Sprintf("Unknown server %s", id))}
+	}
+
+	return []*discordgo.MessageEmbed{c.summaryEmbed()}
+}
+
+// statusEmbed creates an embed with detailed information about one server
+func (t *TemplateData) statusEmbed(updated time.Time) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title:     t.ID,
+		Color:     colorOffline,
+		Timestamp: updated.Format(time.RFC3339),
+		Footer:    &discordgo.MessageEmbedFooter{Text: t.ID},
+	}
+
+	address := fmt.Sprintf("%s:%d", t.Host, t.Port)
+	if t.Info == nil {
+		embed.Description = "🔴 Server offline"
+		embed.Fields = []*discordgo.MessageEmbedField{
+			{Name: "Address", Value: address, Inline: true},
+		}
+		return embed
+	}
+
+	if t.Info.Port != 0 {
+		address = fmt.Sprintf("%s:%d", t.Host, t.Info.Port)
+	}
+
+	embed.Title = t.Info.Name
+	embed.Color = colorOnline
+	embed.Description = "🟢 Server online"
+	embed.Fields = []*discordgo.MessageEmbedField{
+		{Name: "Players", Value: t.playersString(), Inline: true},
+		{Name: "Map", Value: orDash(t.Info.Map), Inline: true},
+		{Name: "Game", Value: orDash(appid.AppID(t.Info.ID).String()), Inline: true},
+		{Name: "Address", Value: address, Inline: true},
+		{Name: "Version", Value: orDash(t.Info.Version), Inline: true},
+	}
+
+	return embed
+}
+
+// summaryEmbed creates an embed with one line per server
+func (c *Config) summaryEmbed() *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{Title: "Servers status"}
+
+	var online int
+	var updated time.Time
+	for _, srv := range c.Servers {
+		if len(embed.Fields) >= maxEmbedFields {
+			break
+		}
+
+		value := "⚪ No data yet"
+		if cached, ok := dataCache.get(srv.ID); ok {
+			if cached.UpdatedAt.After(updated) {
+				updated = cached.UpdatedAt
+			}
+
+			if cached.Tpl.Info != nil {
+				online++
+				value = fmt.Sprintf("🟢 %s on %s", cached.Tpl.playersString(), orDash(cached.Tpl.Info.Map))
+			} else {
+				value = "🔴 Offline"
+			}
+		}
+
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: srv.ID, Value: value})
+	}
+
+	switch {
+	case online == 0:
+		embed.Color = colorOffline
+	case online < len(c.Servers):
+		embed.Color = colorPartial
+	default:
+		embed.Color = colorOnline
+	}
+
+	embed.Description = fmt.Sprintf("%d/%d servers online", online, len(c.Servers))
+	if !updated.IsZero() {
+		embed.Timestamp = updated.Format(time.RFC3339)
+	}
+
+	return embed
+}
+
+// uptimeEmbed creates an embed with the uptime of one or all servers
+func (c *Config) uptimeEmbed(id string) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{Title: "Uptime", Color: colorOnline}
+
+	for _, srv := range c.Servers {
+		if id != "" && srv.ID != id {
+			continue
+		}
+		if len(embed.Fields) >= maxEmbedFields {
+			break
+		}
+
+		value := "⚪ No data yet"
+		if up := uptimes.get(srv.ID); up != nil {
+			parts := make([]string, len(uptimeWindows))
+			for i, percent := range up.windows() {
+				parts[i] = fmt.Sprintf("%s **%.2f%%**", uptimeWindows[i], percent)
+			}
+			value = fmt.Sprintf("%s\nTracked since <t:%d:f>", strings.Join(parts, " · "), up.Since.Unix())
+
+			if up.Month < 100 && embed.Color == colorOnline {
+				embed.Color = colorPartial
+			}
+		}
+
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: srv.ID, Value: value})
+	}
+
+	if len(embed.Fields) == 0 {
+		return messageEmbed(fmt.Sprintf("Unknown server %s", id))
+	}
+
+	return embed
+}
+
+// serverChoices returns autocomplete choices of server IDs containing the typed value
+func (c *Config) serverChoices(value string) []*discordgo.ApplicationCommandOptionChoice {
+	value = strings.ToLower(value)
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, maxChoices)
+
+	for _, srv := range c.Servers {
+		if len(choices) >= maxChoices {
+			break
+		}
+		if !strings.Contains(strings.ToLower(srv.ID), value) {
+			continue
+		}
+
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: truncate(srv.ID, maxChoiceLength), Value: srv.ID})
+	}
+
+	return choices
+}
+
+// playersString returns players and slots with the queue length for DayZ
+func (t *TemplateData) playersString() string {
+	if t.Info == nil {
+		return "-"
+	}
+
+	if dayz, ok := t.Extra.(*keywords.DayZ); ok && dayz.PlayersQueue > 0 {
+		return fmt.Sprintf("%d/%d (+%d)", t.Info.Players, t.Info.MaxPlayers, dayz.PlayersQueue)
+	}
+
+	return fmt.Sprintf("%d/%d", t.Info.Players, t.Info.MaxPlayers)
+}
+
+// respondEmbeds answers the interaction with embeds
+func respondEmbeds(ds *discordgo.Session, i *discordgo.InteractionCreate, embeds []*discordgo.MessageEmbed) {
+	err := ds.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Embeds: embeds},
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Error responding to interaction")
+	}
+}
+
+/*
+respondGraph answers the interaction with the graph of players of one or all servers.
+
+The graph is drawn from the stored samples, or from the in-memory history without the sample store,
+ranges longer than the in-memory history are answered with an error then.
+*/
+func respondGraph(ds *discordgo.Session, i *discordgo.InteractionCreate, cfg *Config, id, rangeName string) {
+	if rangeName == "" {
+		rangeName = defaultGraphRange
+	}
+
+	var ids []string
+	for _, srv := range cfg.Servers {
+		if id == "" || srv.ID == id {
+			ids = append(ids, srv.ID)
+		}
+	}
+	if len(ids) == 0 {
+		respondEmbeds(ds, i, []*discordgo.MessageEmbed{messageEmbed(fmt.Sprintf("Unknown server %s", id))})
+		return
+	}
+	if period, ok := graphRanges[rangeName]; ok && !cfg.History.keeps(period) {
+		respondEmbeds(ds, i, []*discordgo.MessageEmbed{messageEmbed(fmt.Sprintf("Range %s is not available without the sample store", rangeName))})
+		return
+	}
+
+	// Rendering may take longer than the interaction response deadline, the graph is sent as an edit
+	err := ds.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Error responding to interaction")
+		return
+	}
+
+	edit := &discordgo.WebhookEdit{}
+	data, err := serverGraph(ids, rangeName)
+	if err != nil {
+		log.Error().Err(err).Strs("servers", ids).Msg("Failed to render graph")
+		edit.Embeds = &[]*discordgo.MessageEmbed{messageEmbed("Failed to render graph")}
+	} else {
+		edit.Embeds = &[]*discordgo.MessageEmbed{{Image: &discordgo.MessageEmbedImage{URL: "attachment://" + graphFile}}}
+		edit.Files = []*discordgo.File{{Name: graphFile, ContentType: "image/png", Reader: bytes.NewReader(data)}}
+	}
+
+	if _, err := ds.InteractionResponseEdit(i.Interaction, edit); err != nil {
+		log.Error().Err(err).Msg("Error editing interaction response")
+	}
+}
+
+// respondChoices answers the autocomplete interaction with choices
+func respondChoices(ds *discordgo.Session, i *discordgo.InteractionCreate, choices []*discordgo.ApplicationCommandOptionChoice) {
+	err := ds.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{Choices: choices},
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Error responding to autocomplete")
+	}
+}
+
+// optionString returns the string value of the named option or empty string
+func optionString(options []*discordgo.ApplicationCommandInteractionDataOption, name string) string {
+	for _, opt := range options {
+		if opt.Name == name {
+			return opt.StringValue()
+		}
+	}
+
+	return ""
+}
+
+// messageEmbed creates a simple embed with only a description
+func messageEmbed(text string) *discordgo.MessageEmbed {
+	return &discordgo.MessageEmbed{Description: text, Color: colorPartial}
+}
+
+// orDash returns "-" for empty strings, Discord rejects embed fields with empty values
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
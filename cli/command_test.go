@@ -0,0 +1,95 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/woozymasta/a2s/pkg/a2s"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		in    string
+		limit int
+		want  string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"longer than ten", 10, "longer ..."},
+		// "ж" is two bytes, the cut backs off to the start of the rune
+		{strings.Repeat("ж", 10), 10, "жжж..."},
+		{"🟢🟢🟢", 8, "🟢..."},
+	}
+
+	for _, tt := range tests {
+		got := truncate(tt.in, tt.limit)
+		if got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, expected %q", tt.in, tt.limit, got, tt.want)
+		}
+		if len(got) > tt.limit || !utf8.ValidString(got) {
+			t.Errorf("truncate(%q, %d) = %q is longer than the limit or not valid UTF-8", tt.in, tt.limit, got)
+		}
+	}
+}
+
+func TestServerChoices(t *testing.T) {
+	cfg := &Config{Servers: []ServerConfig{{ID: "Chernarus"}, {ID: "livonia"}, {ID: strings.Repeat("ж", 60)}}}
+
+	choices := cfg.serverChoices("CHER")
+	if len(choices) != 1 || choices[0].Value != "Chernarus" {
+		t.Errorf("choices = %+v, expected Chernarus", choices)
+	}
+
+	if choices = cfg.serverChoices(""); len(choices) != 3 {
+		t.Fatalf("choices = %d, expected all servers", len(choices))
+	}
+	if name := choices[2].Name; len(name) > maxChoiceLength || !utf8.ValidString(name) {
+		t.Errorf("choice name %q is longer than %d or not valid UTF-8", name, maxChoiceLength)
+	}
+	if choices[2].Value != cfg.Servers[2].ID {
+		t.Errorf("choice value is cut")
+	}
+}
+
+func TestStatusEmbeds(t *testing.T) {
+	cfg := &Config{Servers: []ServerConfig{{ID: "on"}, {ID: "off"}, {ID: "new"}}}
+
+	dataCache.set(&TemplateData{ID: "on", Host: "127.0.0.1", Port: 2302, Info: &a2s.Info{Name: "Online", Map: "chernarusplus", Players: 5, MaxPlayers: 60}})
+	dataCache.set(&TemplateData{ID: "off", Host: "127.0.0.1", Port: 2402})
+	t.Cleanup(func() {
+		dataCache.delete("on")
+		dataCache.delete("off")
+	})
+
+	tests := []struct {
+		id    string
+		title string
+		color int
+		text  string // Substring of the description
+	}{
+		{"on", "Online", colorOnline, "online"},
+		{"off", "off", colorOffline, "offline"},
+		{"new", "", colorPartial, "No data"},
+		{"unknown", "", colorPartial, "Unknown server"},
+		{"", "Servers status", colorPartial, "1/3 servers online"},
+	}
+
+	for _, tt := range tests {
+		embeds := cfg.statusEmbeds(tt.id)
+		if len(embeds) != 1 {
+			t.Fatalf("%q: embeds = %d, expected 1", tt.id, len(embeds))
+		}
+
+		e := embeds[0]
+		if e.Title != tt.title || e.Color != tt.color || !strings.Contains(e.Description, tt.text) {
+			t.Errorf("%q: embed = %q %#x %q, expected %q %#x with %q", tt.id, e.Title, e.Color, e.Description, tt.title, tt.color, tt.text)
+		}
+	}
+
+	// The summary shows players and map of online servers
+	summary := cfg.summaryEmbed()
+	if len(summary.Fields) != 3 || summary.Fields[0].Value != "🟢 5/60 on chernarusplus" {
+		t.Errorf("summary fields = %+v", summary.Fields)
+	}
+}
@@ -1,11 +1,17 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"strings"
+	"text/template"
 	"time"
 
 	"github.com/mcuadros/go-defaults"
+	"github.com/rs/zerolog/log"
+	"github.com/woozymasta/a2s/pkg/a2s"
 	"gopkg.in/yaml.v3"
 )
 
@@ -16,15 +22,20 @@ It includes bot settings, a list of servers to monitor,
 logging configuration, and other relevant parameters.
 */
 type Config struct {
-	Logging Logging        `yaml:"logging,omitempty"` // Logging configuration
-	Servers []ServerConfig `yaml:"servers"`           // List of server configurations
-	Bot     struct {
-		Token          string        `yaml:"token"`                         // Discord bot token
-		UpdateInterval time.Duration `yaml:"update_interval" default:"30s"` // Interval for status updates
-		Concurrency    int           `yaml:"concurrency" default:"10"`      // Number of concurrent operations
-	} `yaml:"bot"`
-
-	prevCumulativeOnline int // Internal state to track previous cumulative online count
+	StatusMessage *StatusMessage    `yaml:"status_message,omitempty"` // Status message for all servers
+	Logging       Logging           `yaml:"logging,omitempty"`        // Logging configuration
+	Alerts        Alerts            `yaml:"alerts,omitempty"`         // Online/offline alerts configuration
+	PlayerEvents  PlayerEvents      `yaml:"player_events,omitempty"`  // Feed of players joining and leaving
+	HTTP          HTTP              `yaml:"http,omitempty"`           // Built-in HTTP server configuration
+	History       HistoryConfig     `yaml:"history,omitempty"`        // In-memory history exposed to templates
+	Servers       []ServerConfig    `yaml:"servers"`                  // List of server configurations
+	Bots          []BotIdentity     `yaml:"bots,omitempty"`           // Additional bots bound to a subset of servers
+	Reports       []Report          `yaml:"reports,omitempty"`        // Scheduled summary reports
+	Templates     map[string]string `yaml:"templates,omitempty"`      // Shared named templates used with {{ template "name" . }}
+	TemplatesDir  string            `yaml:"templates_dir,omitempty"`  // Directory with *.tmpl shared templates and base for relative *_file paths
+	Bot           BotConfig         `yaml:"bot"`                      // Main bot, or a list of bots with the main bot first
+
+	templateFiles []string // Paths of the loaded template files and directory, watched for changes
 }
 
 /*
@@ -35,57 +46,257 @@ It includes server connection details and Discord channel/category settings.
 type ServerConfig struct {
 	// Configuration data
 
-	ID           string `yaml:"id"`                            // Unique identifier for the server
-	Host         string `yaml:"host" default:"127.0.0.1"`      // Server host address
-	ChannelID    string `yaml:"channel_id,omitempty"`          // Discord channel ID to update
-	ChannelName  string `yaml:"channel_name,omitempty"`        // Template for channel name
-	ChannelDesc  string `yaml:"channel_description,omitempty"` // Template for channel description
-	CategoryID   string `yaml:"category_id,omitempty"`         // Discord category ID to update
-	CategoryName string `yaml:"category_name,omitempty"`       // Template for category name
-	Port         int    `yaml:"port" default:"27016"`          // Server port
-	Timeout      int    `yaml:"timeout" default:"3"`           // Timeout in seconds for A2S queries
+	StatusMessage *StatusMessage `yaml:"status_message,omitempty"` // Status message for this server
+
+	ID           string `yaml:"id"`                                 // Unique identifier for the server
+	Host         string `yaml:"host" default:"127.0.0.1"`           // Server host address
+	ChannelID    string `yaml:"channel_id,omitempty"`               // Discord channel ID to update
+	ChannelName  string `yaml:"channel_name,omitempty"`             // Template for channel name
+	ChannelDesc  string `yaml:"channel_description,omitempty"`      // Template for channel description
+	CategoryID   string `yaml:"category_id,omitempty"`              // Discord category ID to update
+	CategoryName string `yaml:"category_name,omitempty"`            // Template for category name
+	AlertsChanID string `yaml:"alerts_channel_id,omitempty"`        // Discord channel ID for alerts (overrides alerts.channel_id)
+	EventsChanID string `yaml:"player_events_channel_id,omitempty"` // Discord channel ID for player events (overrides player_events.channel_id)
+	Port         int    `yaml:"port" default:"27016"`               // Server port
+	Timeout      int    `yaml:"timeout" default:"3"`                // Timeout in seconds for A2S queries
+
+	// Files to read templates from instead of the inline ones
+
+	ChannelNameFile  string `yaml:"channel_name_file,omitempty"`        // File with template for channel name
+	ChannelDescFile  string `yaml:"channel_description_file,omitempty"` // File with template for channel description
+	CategoryNameFile string `yaml:"category_name_file,omitempty"`       // File with template for category name
+
+	// Fields to track the server state for alerts
 
-	// Fields to store the previous state hashes for channels and categories
+	failedSince time.Time   // Time of the first failed query in a row
+	failures    int         // Number of consecutive failed queries
+	state       serverState // Last known server state
+	alerted     bool        // Offline alert of the current outage was sent
 
-	prevChannelHash  uint64 // Previous hash for the channel
-	prevCategoryHash uint64 // Previous hash for the category
+	// Fields to track players for player events
+
+	players      []a2s.Player // Players with names of the last A2S_PLAYER result
+	playersKnown bool         // At least one A2S_PLAYER result was received
+
+	// Compiled templates
+
+	tplChannelName  *template.Template // Compiled ChannelName
+	tplChannelDesc  *template.Template // Compiled ChannelDesc
+	tplCategoryName *template.Template // Compiled CategoryName
 
 	// Configuration data again (aligned)
 
-	BufferSize uint16 `yaml:"buffer_size" default:"1024"` // Buffer size for A2S queries
+	BufferSize   uint16 `yaml:"buffer_size" default:"1024"` // Buffer size for A2S queries
+	QueryPlayers bool   `yaml:"query_players,omitempty"`    // Also query A2S_PLAYER for the player list
+	QueryRules   bool   `yaml:"query_rules,omitempty"`      // Also query A2S_RULES for the server rules
 }
 
 /*
-readConfig reads and parses the configuration file.
-
-It loads the YAML configuration from the specified path,
-applies default values, and sets up logging.
+readConfig reads the configuration and sets up logging.
 
 The function returns a pointer to a Config struct and an error if the operation fails.
 */
 func readConfig() (*Config, error) {
-	var path = "config.yaml"
-	if len(os.Args) > 1 {
-		path = os.Args[1]
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
 	}
 
-	data, err := os.ReadFile(path)
+	cfg.Logging.setup()
+
+	return cfg, nil
+}
+
+/*
+loadConfig reads the configuration and compiles all templates.
+
+Templates are compiled once here, so syntax errors are reported at startup
+or reload instead of being rendered into Discord channels.
+*/
+func loadConfig() (*Config, error) {
+	cfg, err := parseConfig()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read configuration file: %w", err)
+		return nil, err
+	}
+
+	if err := cfg.compileTemplates(); err != nil {
+		return nil, fmt.Errorf("failed to compile templates: %w", err)
 	}
 
+	return cfg, nil
+}
+
+/*
+parseConfig reads and parses the configuration file and environment variables.
+
+It loads the YAML configuration from the specified path, overrides it with
+environment variables, applies default values, validates the result and
+reads templates from files.
+If the path is not passed and the default config.yaml does not exist,
+the configuration is read only from environment variables.
+*/
+func parseConfig() (*Config, error) {
 	var cfg Config
-	err = yaml.Unmarshal(data, &cfg)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse configuration: %w", err)
+
+	path, explicit := configPath()
+	data, err := os.ReadFile(path) // #nosec G304
+	switch {
+	case err == nil:
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse configuration: %w", err)
+		}
+	case !explicit && errors.Is(err, fs.ErrNotExist):
+		log.Debug().Str("path", path).Msg("Configuration file not found, using only environment variables")
+	default:
+		return nil, fmt.Errorf("failed to read configuration file: %w", err)
 	}
 
-	if cfg.Bot.Token == "" {
-		return nil, fmt.Errorf("bot token is empty")
+	// Additional bots from the list form of the bot key go before the bots list
+	cfg.Bots = append(cfg.Bot.bots, cfg.Bots...)
+	cfg.Bot.bots = nil
+
+	if err := readEnv(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to read environment variables: %w", err)
 	}
 
 	defaults.SetDefaults(&cfg)
-	cfg.Logging.setup()
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.readTemplateFiles(); err != nil {
+		return nil, err
+	}
+
+	for i := range cfg.Servers {
+		if cfg.Servers[i].StatusMessage != nil {
+			cfg.Servers[i].StatusMessage.server = cfg.Servers[i].ID
+		}
+	}
 
 	return &cfg, nil
 }
+
+// configFile is the path to the configuration file passed to a command like --validate
+var configFile string
+
+// configPath returns the path to the configuration file and whether it was passed explicitly
+func configPath() (string, bool) {
+	if configFile != "" {
+		return configFile, true
+	}
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		return os.Args[1], true
+	}
+
+	return "config.yaml", false
+}
+
+// validate checks the configuration for errors that make it unusable
+func (c *Config) validate() error {
+	if c.Bot.Token == "" {
+		return fmt.Errorf("bot token is empty")
+	}
+
+	ids := make(map[string]struct{}, len(c.Servers))
+	for i, srv := range c.Servers {
+		if srv.ID == "" {
+			return fmt.Errorf("server #%d has empty id", i)
+		}
+		if _, ok := ids[srv.ID]; ok {
+			return fmt.Errorf("duplicate server id %q", srv.ID)
+		}
+		ids[srv.ID] = struct{}{}
+
+		if err := srv.StatusMessage.validate(&c.History); err != nil {
+			return fmt.Errorf("server %q status_message: %w", srv.ID, err)
+		}
+	}
+
+	if err := c.StatusMessage.validate(&c.History); err != nil {
+		return fmt.Errorf("status_message: %w", err)
+	}
+
+	if err := c.History.validate(); err != nil {
+		return err
+	}
+
+	if err := c.PlayerEvents.validate(); err != nil {
+		return err
+	}
+
+	for i := range c.Reports {
+		if err := c.Reports[i].validate(ids, &c.History); err != nil {
+			return fmt.Errorf("reports #%d: %w", i, err)
+		}
+	}
+
+	if err := validatePresence(&c.Bot.Presence, c.Bot.Servers, ids); err != nil {
+		return fmt.Errorf("bot: %w", err)
+	}
+
+	return c.validateBots(ids)
+}
+
+// validateBots checks tokens of additional bots and that every server is bound to at most one of them
+func (c *Config) validateBots(ids map[string]struct{}) error {
+	tokens := map[string]struct{}{c.Bot.Token: {}}
+	owners := make(map[string]int, len(ids))
+
+	for i, bot := range c.Bots {
+		if bot.Token == "" {
+			return fmt.Errorf("bots #%d has empty token", i)
+		}
+		if _, ok := tokens[bot.Token]; ok {
+			return fmt.Errorf("bots #%d has duplicate token", i)
+		}
+		tokens[bot.Token] = struct{}{}
+
+		if len(bot.Servers) == 0 {
+			return fmt.Errorf("bots #%d has no servers", i)
+		}
+		if err := validatePresence(&c.Bots[i].Presence, bot.Servers, ids); err != nil {
+			return fmt.Errorf("bots #%d: %w", i, err)
+		}
+
+		for _, id := range bot.Servers {
+			if j, ok := owners[id]; ok {
+				return fmt.Errorf("server %q is bound to both bots #%d and #%d", id, j, i)
+			}
+			owners[id] = i
+		}
+	}
+
+	return nil
+}
+
+// serverIDSet returns the set of configured server IDs
+func (c *Config) serverIDSet() map[string]struct{} {
+	ids := make(map[string]struct{}, len(c.Servers))
+	for _, srv := range c.Servers {
+		ids[srv.ID] = struct{}{}
+	}
+
+	return ids
+}
+
+// validatePresence checks the presence and that all referenced server IDs exist
+func validatePresence(p *Presence, servers []string, ids map[string]struct{}) error {
+	for _, id := range servers {
+		if _, ok := ids[id]; !ok {
+			return fmt.Errorf("unknown server id %q", id)
+		}
+	}
+
+	for i, r := range p.Rotate {
+		if r.Server == "" || r.Server == rotateEachServer {
+			continue
+		}
+		if _, ok := ids[r.Server]; !ok {
+			return fmt.Errorf("presence rotate #%d refers to unknown server id %q", i, r.Server)
+		}
+	}
+
+	return p.validate()
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronAliases are the shortcuts of common schedules
+var cronAliases = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// cronField is the range of values of one schedule field
+type cronField struct {
+	name     string
+	min, max int
+}
+
+// cronFields are the fields of the schedule in order
+var cronFields = [5]cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+/*
+cronSchedule is a parsed cron-like schedule with minute precision.
+
+Fields are minute, hour, day of month, month and day of week (0 or 7 is Sunday),
+each is "*", a number, a range "1-5", a list "1,15" or a step "*\/15".
+As in cron, if both days are restricted, either of them matches, a day field
+starting with "*" like "*\/2" is not restricted.
+*/
+type cronSchedule struct {
+	fields  [5]uint64 // Bit sets of allowed values by field
+	anyDay  bool      // Day of month starts with "*"
+	anyWeek bool      // Day of week starts with "*"
+}
+
+// parseCron parses the schedule of five fields or one of the aliases like @daily
+func parseCron(spec string) (*cronSchedule, error) {
+	if alias, ok := cronAliases[strings.TrimSpace(spec)]; ok {
+		spec = alias
+	}
+
+	parts := strings.Fields(spec)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("invalid schedule %q, expected 5 fields: minute hour day month weekday", spec)
+	}
+
+	s := &cronSchedule{anyDay: strings.HasPrefix(parts[2], "*"), anyWeek: strings.HasPrefix(parts[4], "*")}
+	for i, part := range parts {
+		bits, err := cronFields[i].parse(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+		s.fields[i] = bits
+	}
+
+	// Sunday is both 0 and 7
+	if s.fields[4]&(1<<7) != 0 {
+		s.fields[4] |= 1
+	}
+
+	return s, nil
+}
+
+// parse returns the bit set of values of the comma separated list of the field
+func (f cronField) parse(part string) (uint64, error) {
+	var bits uint64
+
+	for _, item := range strings.Split(part, ",") {
+		rng, stepStr, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid %s step %q", f.name, stepStr)
+			}
+			step = n
+		}
+
+		lo, hi := f.min, f.max
+		if rng != "*" {
+			from, to, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = f.value(from); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = f.value(to); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				hi = f.max
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid %s range %q", f.name, rng)
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+
+	return bits, nil
+}
+
+// value parses one value of the field and checks its range
+func (f cronField) value(s string) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid %s %q, expected %d-%d", f.name, s, f.min, f.max)
+	}
+
+	return v, nil
+}
+
+// matches reports whether the schedule fires in the minute of the time
+func (s *cronSchedule) matches(t time.Time) bool {
+	if !s.has(0, t.Minute()) || !s.has(1, t.Hour()) || !s.has(3, int(t.Month())) {
+		return false
+	}
+
+	day, week := s.has(2, t.Day()), s.has(4, int(t.Weekday()))
+	switch {
+	case s.anyDay || s.anyWeek:
+		return day && week
+	default:
+		return day || week
+	}
+}
+
+// has reports whether the value is allowed in the field
+func (s *cronSchedule) has(field, v int) bool {
+	return s.fields[field]&(1<<v) != 0
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	// 2025-06-01 is Sunday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2025, time.June, day, hour, minute, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		spec  string
+		match []time.Time
+		skip  []time.Time
+	}{
+		{
+			spec:  "*/15 * * * *",
+			match: []time.Time{at(2, 10, 0), at(2, 10, 15), at(2, 10, 45)},
+			skip:  []time.Time{at(2, 10, 20), at(2, 10, 59)},
+		},
+		{
+			spec:  "1-5/2 * * * *",
+			match: []time.Time{at(2, 10, 1), at(2, 10, 3), at(2, 10, 5)},
+			skip:  []time.Time{at(2, 10, 0), at(2, 10, 2), at(2, 10, 7)},
+		},
+		{
+			spec:  "5/20 9 * * *",
+			match: []time.Time{at(2, 9, 5), at(2, 9, 25), at(2, 9, 45)},
+			skip:  []time.Time{at(2, 9, 0), at(2, 10, 5)},
+		},
+		{
+			spec:  "0 9,18 * * 1-5",
+			match: []time.Time{at(2, 9, 0), at(6, 18, 0)},
+			skip:  []time.Time{at(1, 9, 0), at(7, 18, 0), at(2, 12, 0)},
+		},
+		{
+			spec:  "0 0 * * 7",
+			match: []time.Time{at(1, 0, 0), at(8, 0, 0)},
+			skip:  []time.Time{at(2, 0, 0), at(7, 0, 0)},
+		},
+		{
+			// Both days restricted, either of them matches
+			spec:  "0 0 1 * 1",
+			match: []time.Time{at(1, 0, 0), at(2, 0, 0), at(9, 0, 0)},
+			skip:  []time.Time{at(3, 0, 0), at(8, 0, 0)},
+		},
+		{
+			// Day of month starting with "*" is not restricted, both must match
+			spec:  "0 0 */2 * 1",
+			match: []time.Time{at(9, 0, 0), at(23, 0, 0)},
+			skip:  []time.Time{at(2, 0, 0), at(3, 0, 0), at(16, 0, 0)},
+		},
+		{
+			spec:  "@weekly",
+			match: []time.Time{at(1, 0, 0)},
+			skip:  []time.Time{at(2, 0, 0), at(1, 0, 1)},
+		},
+		{
+			spec:  "@monthly",
+			match: []time.Time{at(1, 0, 0)},
+			skip:  []time.Time{at(2, 0, 0)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := parseCron(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, tm := range tt.match {
+				if !s.matches(tm) {
+					t.Errorf("expected match at %s", tm.Format(time.DateTime+" Mon"))
+				}
+			}
+			for _, tm := range tt.skip {
+				if s.matches(tm) {
+					t.Errorf("unexpected match at %s", tm.Format(time.DateTime+" Mon"))
+				}
+			}
+		})
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1-x * * * *",
+		"@yearly",
+	} {
+		if _, err := parseCron(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
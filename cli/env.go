@@ -0,0 +1,286 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+envPrefix is the prefix of all environment variables.
+
+Names of variables are built from the YAML keys of the configuration joined with "_"
+in upper case, list items are addressed by index, for example:
+
+	DISCORD_A2S_BOT_TOKEN
+	DISCORD_A2S_LOGGING_LEVEL
+	DISCORD_A2S_SERVERS_0_PORT
+
+Lists of strings are separated by commas, for example DISCORD_A2S_BOT_SERVERS=alpha,beta.
+*/
+const envPrefix = "DISCORD_A2S"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+/*
+readEnv overrides the configuration with values from environment variables.
+
+Variables for list items extend the list from the configuration file,
+so servers can be fully defined by variables or only partially overridden.
+*/
+func readEnv(cfg *Config) error {
+	_, err := envStruct(reflect.ValueOf(cfg).Elem(), envPrefix, os.Environ())
+	return err
+}
+
+// envStruct sets struct fields from environment, returns true if any variable was found
+func envStruct(v reflect.Value, prefix string, environ []string) (bool, error) {
+	var found bool
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := envFieldName(field)
+		if name == "" {
+			continue
+		}
+
+		ok, err := envValue(v.Field(i), prefix+"_"+name, environ)
+		if err != nil {
+			return false, err
+		}
+		found = found || ok
+	}
+
+	return found, nil
+}
+
+// envValue sets one value from environment by its type, returns true if any variable was found
+func envValue(v reflect.Value, name string, environ []string) (bool, error) {
+	switch {
+	case v.Kind() == reflect.Struct:
+		return envStruct(v, name, environ)
+
+	case v.Kind() == reflect.Ptr && v.Type().Elem().Kind() == reflect.Struct:
+		elem := reflect.New(v.Type().Elem())
+		if !v.IsNil() {
+			elem = v
+		}
+
+		found, err := envStruct(elem.Elem(), name, environ)
+		if err != nil {
+			return false, err
+		}
+		if found {
+			v.Set(elem)
+		}
+		return found, nil
+
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Struct:
+		return envSlice(v, name, environ)
+
+	case v.Kind() == reflect.Map:
+		// Keys of maps like shared templates can't be expressed in variable names
+		return false, nil
+	}
+
+	value, ok := lookupEnv(environ, name)
+	if !ok {
+		return false, nil
+	}
+
+	if err := setValue(v, value); err != nil {
+		return false, fmt.Errorf("invalid value of environment variable %s: %w", name, err)
+	}
+
+	return true, nil
+}
+
+/*
+envSlice sets slice items from environment, items are added up to the highest index found.
+
+Indexes of added items must have no gaps, a skipped index is an error instead of
+an empty item or silently ignored variables.
+*/
+func envSlice(v reflect.Value, name string, environ []string) (bool, error) {
+	var found bool
+
+	last := envMaxIndex(environ, name)
+	for i := 0; i < v.Len() || i <= last; i++ {
+		prefix := name + "_" + strconv.Itoa(i)
+		if i >= v.Len() && !hasEnvPrefix(environ, prefix+"_") {
+			return false, fmt.Errorf("environment variables %s_* are missing, list items must be numbered without gaps", prefix)
+		}
+
+		item := reflect.New(v.Type().Elem()).Elem()
+		if i < v.Len() {
+			item = v.Index(i)
+		}
+
+		ok, err := envStruct(item, prefix, environ)
+		if err != nil {
+			return false, err
+		}
+
+		if i >= v.Len() {
+			v.Set(reflect.Append(v, item))
+		}
+		found = found || ok
+	}
+
+	return found, nil
+}
+
+// setValue parses the string to the value by its kind
+func setValue(v reflect.Value, value string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+// lookupEnv returns the value of the variable from the environment list
+func lookupEnv(environ []string, name string) (string, bool) {
+	for _, env := range environ {
+		if key, value, ok := strings.Cut(env, "="); ok && key == name {
+			return value, true
+		}
+	}
+
+	return "", false
+}
+
+// envMaxIndex returns the highest index of list items NAME_<index>_* in environment, -1 if none
+func envMaxIndex(environ []string, name string) int {
+	last := -1
+	for _, env := range environ {
+		rest, ok := strings.CutPrefix(env, name+"_")
+		if !ok {
+			continue
+		}
+
+		index, _, ok := strings.Cut(rest, "_")
+		if i, err := strconv.Atoi(index); ok && err == nil && i >= 0 {
+			last = max(last, i)
+		}
+	}
+
+	return last
+}
+
+// hasEnvPrefix checks if any environment variable starts with the prefix
+func hasEnvPrefix(environ []string, prefix string) bool {
+	for _, env := range environ {
+		if strings.HasPrefix(env, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// envFieldName returns the variable name part for the struct field from its YAML tag
+func envFieldName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "" || name == "-" {
+		return ""
+	}
+
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+/*
+printEnv prints all supported environment variables with default values.
+
+List items are printed once with index 0 as an example.
+*/
+func printEnv() {
+	var sb strings.Builder
+	envDescribe(&sb, reflect.TypeOf(Config{}), envPrefix)
+	fmt.Print(sb.String())
+	os.Exit(0)
+}
+
+// envDescribe writes NAME=default lines for all fields of the struct type
+func envDescribe(sb *strings.Builder, t reflect.Type, prefix string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := envFieldName(field)
+		if name == "" {
+			continue
+		}
+		name = prefix + "_" + name
+
+		ft := field.Type
+		switch {
+		case ft.Kind() == reflect.Struct:
+			envDescribe(sb, ft, name)
+		case ft.Kind() == reflect.Ptr && ft.Elem().Kind() == reflect.Struct:
+			envDescribe(sb, ft.Elem(), name)
+		case ft.Kind() == reflect.Slice && ft.Elem().Kind() == reflect.Struct:
+			envDescribe(sb, ft.Elem(), name+"_0")
+		case ft.Kind() == reflect.Map:
+			continue
+		case ft.Kind() == reflect.Slice:
+			// Defaults of lists are written as [a,b], environment variables use a,b
+			fmt.Fprintf(sb, "%s=%s\n", name, strings.Trim(field.Tag.Get("default"), "[]"))
+		default:
+			fmt.Fprintf(sb, "%s=%s\n", name, field.Tag.Get("default"))
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnvStructNested(t *testing.T) {
+	var cfg Config
+	environ := []string{
+		"DISCORD_A2S_BOT_TOKEN=secret",
+		"DISCORD_A2S_BOT_UPDATE_INTERVAL=1m",
+		"DISCORD_A2S_BOT_SERVERS=alpha, beta,",
+		"DISCORD_A2S_BOT_PRESENCE_ONLINE=online",
+		"DISCORD_A2S_LOGGING_LEVEL=debug",
+		"DISCORD_A2S_STATUS_MESSAGE_CHANNEL_ID=42",
+		"OTHER_VARIABLE=ignored",
+	}
+
+	found, err := envStruct(reflect.ValueOf(&cfg).Elem(), envPrefix, environ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found {
+		t.Fatal("expected variables to be found")
+	}
+
+	if cfg.Bot.Token != "secret" {
+		t.Errorf("token = %q", cfg.Bot.Token)
+	}
+	if cfg.Bot.UpdateInterval != time.Minute {
+		t.Errorf("update interval = %s", cfg.Bot.UpdateInterval)
+	}
+	if !reflect.DeepEqual(cfg.Bot.Servers, []string{"alpha", "beta"}) {
+		t.Errorf("servers = %q", cfg.Bot.Servers)
+	}
+	if cfg.Bot.Presence.Online != "online" {
+		t.Errorf("presence online = %q", cfg.Bot.Presence.Online)
+	}
+	if cfg.Logging.Level != "debug" {
+		t.Errorf("logging level = %q", cfg.Logging.Level)
+	}
+	if cfg.StatusMessage == nil || cfg.StatusMessage.ChannelID != "42" {
+		t.Errorf("status message = %+v", cfg.StatusMessage)
+	}
+	if cfg.Servers != nil {
+		t.Errorf("servers list = %+v, expected nil", cfg.Servers)
+	}
+}
+
+func TestEnvSlice(t *testing.T) {
+	tests := []struct {
+		name    string
+		servers []ServerConfig
+		environ []string
+		want    []string // IDs of servers
+		port    int      // Port of the first server
+		err     string
+	}{
+		{
+			name:    "added from environment",
+			environ: []string{"DISCORD_A2S_SERVERS_0_ID=a", "DISCORD_A2S_SERVERS_1_ID=b"},
+			want:    []string{"a", "b"},
+		},
+		{
+			name:    "existing item overridden",
+			servers: []ServerConfig{{ID: "a", Port: 1}},
+			environ: []string{"DISCORD_A2S_SERVERS_0_PORT=2"},
+			want:    []string{"a"},
+			port:    2,
+		},
+		{
+			name:    "appended after existing items",
+			servers: []ServerConfig{{ID: "a"}},
+			environ: []string{"DISCORD_A2S_SERVERS_1_ID=b"},
+			want:    []string{"a", "b"},
+		},
+		{
+			name:    "two digit index",
+			environ: envServers(11),
+			want:    strings.Fields("s0 s1 s2 s3 s4 s5 s6 s7 s8 s9 s10"),
+		},
+		{
+			name:    "gap",
+			environ: []string{"DISCORD_A2S_SERVERS_0_ID=a", "DISCORD_A2S_SERVERS_2_ID=c"},
+			err:     "DISCORD_A2S_SERVERS_1_* are missing",
+		},
+		{
+			name:    "gap after existing items",
+			servers: []ServerConfig{{ID: "a"}},
+			environ: []string{"DISCORD_A2S_SERVERS_2_ID=c"},
+			err:     "DISCORD_A2S_SERVERS_1_* are missing",
+		},
+		{
+			name:    "invalid value",
+			environ: []string{"DISCORD_A2S_SERVERS_0_PORT=port"},
+			err:     "DISCORD_A2S_SERVERS_0_PORT",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Servers: tt.servers}
+			_, err := envStruct(reflect.ValueOf(&cfg).Elem(), envPrefix, tt.environ)
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, expected %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var ids []string
+			for _, srv := range cfg.Servers {
+				ids = append(ids, srv.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("servers = %q, expected %q", ids, tt.want)
+			}
+			if tt.port != 0 && cfg.Servers[0].Port != tt.port {
+				t.Errorf("port = %d, expected %d", cfg.Servers[0].Port, tt.port)
+			}
+		})
+	}
+}
+
+func TestEnvSliceNested(t *testing.T) {
+	var cfg Config
+	environ := []string{
+		"DISCORD_A2S_SERVERS_0_ID=a",
+		"DISCORD_A2S_SERVERS_0_STATUS_MESSAGE_CHANNEL_ID=1",
+		"DISCORD_A2S_BOT_PRESENCE_ROTATE_0_TEMPLATE=first",
+		"DISCORD_A2S_BOT_PRESENCE_ROTATE_1_TEMPLATE=second",
+	}
+
+	if _, err := envStruct(reflect.ValueOf(&cfg).Elem(), envPrefix, environ); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cfg.Servers) != 1 || cfg.Servers[0].StatusMessage == nil || cfg.Servers[0].StatusMessage.ChannelID != "1" {
+		t.Errorf("servers = %+v", cfg.Servers)
+	}
+	if r := cfg.Bot.Presence.Rotate; len(r) != 2 || r[0].Template != "first" || r[1].Template != "second" {
+		t.Errorf("rotate = %+v", r)
+	}
+}
+
+// envServers returns variables of n servers with IDs s0, s1, ...
+func envServers(n int) []string {
+	environ := make([]string, n)
+	for i := range environ {
+		environ[i] = fmt.Sprintf("DISCORD_A2S_SERVERS_%d_ID=s%d", i, i)
+	}
+
+	return environ
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+	"github.com/woozymasta/a2s/pkg/a2s"
+)
+
+const (
+	defaultJoinMessage  = "➡️ **{{ EscapeMarkdown .Player.Name }}** joined {{ .ID }}"
+	defaultLeaveMessage = "⬅️ **{{ EscapeMarkdown .Player.Name }}** left {{ .ID }} after {{ .Player.Duration }}"
+)
+
+/*
+PlayerEvents represents the configuration of the feed of players joining and leaving.
+
+Events are detected by comparing consecutive A2S_PLAYER results of servers with
+query_players, collected and posted to the text channel once per interval, so
+a wave of players after a server restart is one message.
+*/
+type PlayerEvents struct {
+	ChannelID    string        `yaml:"channel_id,omitempty"`              // Discord text channel ID for the feed
+	JoinMessage  string        `yaml:"join_message,omitempty"`            // Template for a player joined event
+	LeaveMessage string        `yaml:"leave_message,omitempty"`           // Template for a player left event
+	Interval     time.Duration `yaml:"interval,omitempty" default:"30s"`  // Interval of posting collected events
+	MaxEvents    int           `yaml:"max_events,omitempty" default:"20"` // Maximum number of events in one post, the rest are counted
+
+	JoinMessageFile  string `yaml:"join_message_file,omitempty"`  // File with template for a player joined event
+	LeaveMessageFile string `yaml:"leave_message_file,omitempty"` // File with template for a player left event
+
+	tplJoin  *template.Template // Compiled JoinMessage or the default one
+	tplLeave *template.Template // Compiled LeaveMessage or the default one
+}
+
+/*
+PlayerEventData represents the data passed to player event templates.
+
+It includes all fields of the server TemplateData and the player. For the left
+event the player is taken from the last result the player was seen in.
+*/
+type PlayerEventData struct {
+	*TemplateData
+	Player a2s.Player // Player who joined or left
+}
+
+// eventFeed collects rendered events by channel until they are posted
+type eventFeed struct {
+	pending map[string][]string // Event lines by channel ID
+	mu      sync.Mutex
+}
+
+// playerEvents is the global feed of player events
+var playerEvents = &eventFeed{pending: make(map[string][]string)}
+
+// validate checks the interval and the limit of events
+func (e *PlayerEvents) validate() error {
+	if e.Interval < time.Second {
+		return fmt.Errorf("player_events interval must be at least 1s")
+	}
+	if e.MaxEvents <= 0 {
+		return fmt.Errorf("player_events max_events must be positive")
+	}
+
+	return nil
+}
+
+/*
+trackPlayers compares the player list with the previous one and adds join and leave events to the feed.
+
+Players with empty names, usually still connecting, are ignored. The first list after
+start is taken silently, lists of failed A2S_PLAYER queries and offline servers are skipped.
+*/
+func (s *ServerConfig) trackPlayers(cfg *Config, tpl *TemplateData) {
+	if !tpl.playersQueried {
+		return
+	}
+
+	cur := make([]a2s.Player, 0, len(tpl.Players))
+	for _, p := range tpl.Players {
+		if strings.TrimSpace(p.Name) != "" {
+			cur = append(cur, p)
+		}
+	}
+
+	prev, known := s.players, s.playersKnown
+	s.players, s.playersKnown = cur, true
+
+	channelID := s.playerEventsChannel(cfg)
+	if !known || channelID == "" {
+		return
+	}
+
+	joined, left := diffPlayers(prev, cur)
+	e := &cfg.PlayerEvents
+	for _, p := range joined {
+		playerEvents.add(channelID, e.render(e.tplJoin, &PlayerEventData{TemplateData: tpl, Player: p}))
+	}
+	for _, p := range left {
+		playerEvents.add(channelID, e.render(e.tplLeave, &PlayerEventData{TemplateData: tpl, Player: p}))
+	}
+}
+
+// playerEventsChannel returns the player events channel of the server or the global one
+func (s *ServerConfig) playerEventsChannel(cfg *Config) string {
+	if s.EventsChanID != "" {
+		return s.EventsChanID
+	}
+
+	return cfg.PlayerEvents.ChannelID
+}
+
+/*
+diffPlayers returns the players who joined and who left between two lists.
+
+Players are matched by name, several players with the same name are counted.
+*/
+func diffPlayers(prev, cur []a2s.Player) (joined, left []a2s.Player) {
+	counts := make(map[string]int, len(prev))
+	for _, p := range prev {
+		counts[p.Name]++
+	}
+
+	for _, p := range cur {
+		if counts[p.Name] > 0 {
+			counts[p.Name]--
+			continue
+		}
+		joined = append(joined, p)
+	}
+
+	for _, p := range prev {
+		if counts[p.Name] > 0 {
+			counts[p.Name]--
+			left = append(left, p)
+		}
+	}
+
+	return joined, left
+}
+
+// render executes the event template, errors are logged and the event is dropped
+func (e *PlayerEvents) render(tmpl *template.Template, data *PlayerEventData) string {
+	line, err := executeTemplate(tmpl, data)
+	if err != nil {
+		log.Error().Err(err).Str("server", data.ID).Msg("Error rendering player event template")
+		return ""
+	}
+
+	return strings.TrimSpace(line)
+}
+
+// add collects the event line for the channel, empty lines are skipped
+func (f *eventFeed) add(channelID, line string) {
+	if line == "" {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pending[channelID] = append(f.pending[channelID], line)
+}
+
+/*
+run posts the collected events every interval of the active configuration.
+
+The interval is read on every iteration, so it follows reloads.
+*/
+func (f *eventFeed) run(ds *discordgo.Session) {
+	for {
+		cfg := activeConfig.Load()
+		time.Sleep(cfg.PlayerEvents.Interval)
+		f.flush(ds, activeConfig.Load().PlayerEvents.MaxEvents)
+	}
+}
+
+/*
+flush posts the collected events of every channel and clears them.
+
+Events above the limit are replaced by their count, the rest are joined by lines
+and split into messages within the Discord limit. Mentions are never allowed,
+as player names are chosen by players.
+*/
+func (f *eventFeed) flush(ds *discordgo.Session, limit int) {
+	f.mu.Lock()
+	pending := f.pending
+	f.pending = make(map[string][]string)
+	f.mu.Unlock()
+
+	for channelID, lines := range pending {
+		if extra := len(lines) - limit; extra > 0 {
+			lines = append(lines[:limit:limit], fmt.Sprintf("… and %d more", extra))
+		}
+
+		if err := sendLines(ds, channelID, lines); err != nil {
+			log.Error().Err(err).Str("channel", channelID).Msg("Failed to send player events")
+			continue
+		}
+
+		log.Debug().Str("channel", channelID).Int("events", len(lines)).Msg("Player events sent")
+	}
+}
+
+// sendLines posts the lines to the channel in as few messages as possible without mentions
+func sendLines(ds *discordgo.Session, channelID string, lines []string) error {
+	for _, content := range splitMessage(lines, maxMessageContent) {
+		ctx, cancel := context.WithTimeout(context.Background(), discordTimeout)
+		_, err := ds.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+			Content:         content,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		}, discordgo.WithContext(ctx))
+		cancel()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// splitMessage joins the lines into messages not longer than the limit, too long lines are cut at a rune boundary
+func splitMessage(lines []string, limit int) []string {
+	var messages []string
+	var b strings.Builder
+
+	for _, line := range lines {
+		line = truncate(line, limit)
+		if b.Len() > 0 && b.Len()+1+len(line) > limit {
+			messages = append(messages, b.String())
+			b.Reset()
+		}
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(line)
+	}
+	if b.Len() > 0 {
+		messages = append(messages, b.String())
+	}
+
+	return messages
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/woozymasta/a2s/pkg/a2s"
+)
+
+func TestSplitMessage(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		limit int
+		want  []string
+	}{
+		{
+			name:  "joined",
+			lines: []string{"aaa", "bbb", "ccc"},
+			limit: 11,
+			want:  []string{"aaa\nbbb\nccc"},
+		},
+		{
+			name:  "split",
+			lines: []string{"aaa", "bbb", "ccc"},
+			limit: 10,
+			want:  []string{"aaa\nbbb", "ccc"},
+		},
+		{
+			name:  "cut",
+			lines: []string{"aaa", "bbbbbbbbbbbb"},
+			limit: 10,
+			want:  []string{"aaa", "bbbbbbb..."},
+		},
+		{
+			// "ж" is two bytes, the cut backs off to the start of the rune
+			name:  "cut at rune boundary",
+			lines: []string{strings.Repeat("ж", 10)},
+			limit: 10,
+			want:  []string{"жжж..."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitMessage(tt.lines, tt.limit)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("messages = %q, expected %q", got, tt.want)
+			}
+			for _, msg := range got {
+				if len(msg) > tt.limit || !utf8.ValidString(msg) {
+					t.Errorf("message %q is longer than %d or not valid UTF-8", msg, tt.limit)
+				}
+			}
+		})
+	}
+}
+
+func TestDiffPlayers(t *testing.T) {
+	players := func(names ...string) []a2s.Player {
+		list := make([]a2s.Player, len(names))
+		for i, name := range names {
+			list[i] = a2s.Player{Name: name}
+		}
+		return list
+	}
+	names := func(list []a2s.Player) []string {
+		var result []string
+		for _, p := range list {
+			result = append(result, p.Name)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name         string
+		prev, cur    []a2s.Player
+		joined, left []string
+	}{
+		{name: "same", prev: players("a", "b"), cur: players("b", "a")},
+		{name: "joined and left", prev: players("a", "b"), cur: players("b", "c"), joined: []string{"c"}, left: []string{"a"}},
+		{name: "same names", prev: players("a", "a", "b"), cur: players("a", "b", "b"), joined: []string{"b"}, left: []string{"a"}},
+		{name: "all left", prev: players("a", "b"), left: []string{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			joined, left := diffPlayers(tt.prev, tt.cur)
+			if got := names(joined); !slices.Equal(got, tt.joined) {
+				t.Errorf("joined = %q, expected %q", got, tt.joined)
+			}
+			if got := names(left); !slices.Equal(got, tt.left) {
+				t.Errorf("left = %q, expected %q", got, tt.left)
+			}
+		})
+	}
+}
+
+func TestEscapeMarkdown(t *testing.T) {
+	tests := map[string]string{
+		"Player":        "Player",
+		"**bold**":      `\*\*bold\*\*`,
+		"_x_ ~~y~~":     `\_x\_ \~\~y\~\~`,
+		"`code` |sp|":   "\\`code\\` \\|sp\\|",
+		"[a](http://b)": `\[a\]\(http://b\)`,
+		"# > - <@1>":    `\# \> \- \<@1\>`,
+		`back\slash`:    `back\\slash`,
+		"Жора":          "Жора",
+	}
+
+	for in, want := range tests {
+		if got := tplHelperEscapeMarkdown(in); got != want {
+			t.Errorf("EscapeMarkdown(%q) = %q, expected %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,420 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Graph image layout
+const (
+	graphWidth  = 800 // Image width in pixels
+	graphHeight = 300 // Image height in pixels
+	graphLeft   = 44  // Left margin for the players axis labels
+	graphRight  = 16  // Right margin
+	graphTop    = 28  // Top margin for the title and legend
+	graphBottom = 22  // Bottom margin for the time axis labels
+
+	graphFile         = "graph.png"      // Name of the attached image
+	defaultGraphRange = "24h"            // Range of the /graph command if not set
+	graphMaxGap       = 30 * time.Minute // Longer periods without samples break the line
+)
+
+// graphRanges are the supported time ranges of graphs by name
+var graphRanges = map[string]time.Duration{
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+}
+
+// Graph colors, close to the Discord dark theme
+var (
+	graphBackground = color.RGBA{0x2B, 0x2D, 0x31, 0xFF}
+	graphGrid       = color.RGBA{0x3F, 0x41, 0x47, 0xFF}
+	graphText       = color.RGBA{0xB5, 0xBA, 0xC1, 0xFF}
+	graphOffline    = color.RGBA{0xED, 0x42, 0x45, 0xFF}
+	graphPalette    = []color.RGBA{
+		{0x58, 0x65, 0xF2, 0xFF}, // Blurple
+		{0x57, 0xF2, 0x87, 0xFF}, // Green
+		{0xFE, 0xE7, 0x5C, 0xFF}, // Yellow
+		{0xEB, 0x45, 0x9E, 0xFF}, // Fuchsia
+		{0x3B, 0xC9, 0xDB, 0xFF}, // Cyan
+		{0xF2, 0x8C, 0x28, 0xFF}, // Orange
+	}
+)
+
+// graphSeries is the samples of one server drawn as one line
+type graphSeries struct {
+	name    string   // Server ID shown in the legend
+	samples []Sample // Samples sorted by time
+}
+
+// graphColumn is the aggregate of samples falling into one pixel column
+type graphColumn struct {
+	time    time.Time // Time of the last sample
+	sum     int       // Sum of players of online samples
+	up      int       // Number of online samples
+	peak    int       // Maximum number of players
+	offline bool      // Column has only offline samples
+	filled  bool      // Column has any samples
+}
+
+// validGraphRange checks the name of the graph range, empty name is allowed
+func validGraphRange(name string) error {
+	if _, ok := graphRanges[name]; name != "" && !ok {
+		return fmt.Errorf("invalid graph range %q, expected %s", name, strings.Join(graphRangeNames(), " or "))
+	}
+
+	return nil
+}
+
+// graphRangeNames returns the names of supported graph ranges sorted by duration
+func graphRangeNames() []string {
+	names := make([]string, 0, len(graphRanges))
+	for name := range graphRanges {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, func(a, b string) int { return int(graphRanges[a] - graphRanges[b]) })
+
+	return names
+}
+
+// graphRangeChoices returns the choices of the range option of the /graph command
+func graphRangeChoices() []*discordgo.ApplicationCommandOptionChoice {
+	names := graphRangeNames()
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, len(names))
+	for i, name := range names {
+		choices[i] = &discordgo.ApplicationCommandOptionChoice{Name: name, Value: name}
+	}
+
+	return choices
+}
+
+/*
+serverGraph renders the graph of players of the servers for the range ending now.
+
+Samples are read from the sample store if it is enabled, from the in-memory history otherwise.
+*/
+func serverGraph(ids []string, rangeName string) ([]byte, error) {
+	period, ok := graphRanges[rangeName]
+	if !ok {
+		return nil, validGraphRange(rangeName)
+	}
+
+	to := time.Now()
+	from := to.Add(-period)
+
+	series := make([]graphSeries, 0, len(ids))
+	for _, id := range ids {
+		samples, err := samplesSince(id, from)
+		if err != nil {
+			return nil, err
+		}
+		series = append(series, graphSeries{name: id, samples: samples})
+	}
+
+	title := "Players, last " + rangeName
+	if len(ids) == 1 {
+		title = ids[0] + " - " + title
+	}
+
+	return renderGraph(title, series, from, to)
+}
+
+// samplesSince returns the samples of the server since the time from the store or the in-memory history
+func samplesSince(id string, since time.Time) ([]Sample, error) {
+	if sampleStore != nil {
+		return sampleStore.read(id, since)
+	}
+
+	return history.samples(id, since), nil
+}
+
+// samples returns a copy of the in-memory samples of the server since the time
+func (h *HistoryStore) samples(id string, since time.Time) []Sample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sh, ok := h.servers[id]
+	if !ok {
+		return nil
+	}
+
+	i, _ := slices.BinarySearchFunc(sh.samples, since, func(s Sample, t time.Time) int { return s.Time.Compare(t) })
+	return slices.Clone(sh.samples[i:])
+}
+
+/*
+renderGraph draws the players of every series between from and to and encodes the image as PNG.
+
+The players axis is scaled to the number of slots. A single series is drawn as
+a filled area of peak players with the line of average players and offline
+periods marked under the time axis, several series are drawn as lines with a legend.
+*/
+func renderGraph(title string, series []graphSeries, from, to time.Time) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, graphWidth, graphHeight))
+	fillRect(img, img.Bounds(), graphBackground)
+
+	plot := image.Rect(graphLeft, graphTop, graphWidth-graphRight, graphHeight-graphBottom)
+	period := to.Sub(from)
+
+	columns := make([][]graphColumn, len(series))
+	var scale, peak int
+	for i, s := range series {
+		columns[i] = graphColumns(s.samples, from, period, plot.Dx())
+		for _, sample := range s.samples {
+			scale = max(scale, sample.MaxPlayers, sample.peak())
+			if sample.Online {
+				peak = max(peak, sample.peak())
+			}
+		}
+	}
+	scale = niceScale(scale)
+
+	drawText(img, 4, 16, fmt.Sprintf("%s, peak %d", title, peak), graphText)
+	drawGrid(img, plot, scale, from, to)
+
+	for i, cols := range columns {
+		c := graphPalette[i%len(graphPalette)]
+		if len(series) == 1 {
+			drawArea(img, plot, cols, scale, c)
+			drawOffline(img, plot, cols)
+		} else {
+			drawLegend(img, i, series[i].name, c)
+		}
+		drawLine(img, plot, cols, scale, c)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode graph: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// graphColumns aggregates the samples into pixel columns of the plot
+func graphColumns(samples []Sample, from time.Time, period time.Duration, width int) []graphColumn {
+	cols := make([]graphColumn, width)
+
+	for _, s := range samples {
+		// Division truncates toward zero, samples just before the start must not fall into the first column
+		x := int(s.Time.Sub(from) * time.Duration(width) / period)
+		if s.Time.Before(from) || x >= width {
+			continue
+		}
+
+		col := &cols[x]
+		if !col.filled {
+			col.offline = true
+		}
+		col.filled = true
+		col.time = s.Time
+
+		if up := s.up(); up > 0 {
+			col.offline = false
+			col.sum += s.Players * up
+			col.up += up
+			col.peak = max(col.peak, s.peak())
+		}
+	}
+
+	return cols
+}
+
+// niceScale rounds the maximum of the players axis up to a multiple of a round step
+func niceScale(v int) int {
+	for _, step := range []int{4, 8, 20, 40, 80, 200, 400, 800} {
+		if v <= step*4 {
+			return max(step, (v+step-1)/step*step)
+		}
+	}
+
+	return (v + 999) / 1000 * 1000
+}
+
+// drawGrid draws the horizontal lines with players and the vertical lines with time labels
+func drawGrid(img *image.RGBA, plot image.Rectangle, scale int, from, to time.Time) {
+	const rows = 4
+	for i := 0; i <= rows; i++ {
+		y := plot.Max.Y - plot.Dy()*i/rows
+		fillRect(img, image.Rect(plot.Min.X, y, plot.Max.X, y+1), graphGrid)
+		label := fmt.Sprint(scale * i / rows)
+		drawText(img, plot.Min.X-6-len(label)*7, y+4, label, graphText)
+	}
+
+	step, layout := 3*time.Hour, "15:04"
+	if to.Sub(from) > 24*time.Hour {
+		step, layout = 24*time.Hour, "Mon 02"
+	}
+
+	// Ticks are aligned to local midnight, so labels are at round hours and day starts
+	y, m, d := from.Date()
+	tick := time.Date(y, m, d, 0, 0, 0, 0, from.Location())
+	for ; !tick.After(to); tick = tick.Add(step) {
+		if tick.Before(from) {
+			continue
+		}
+
+		x := plot.Min.X + int(int64(plot.Dx())*int64(tick.Sub(from))/int64(to.Sub(from)))
+		fillRect(img, image.Rect(x, plot.Min.Y, x+1, plot.Max.Y), graphGrid)
+		label := tick.Format(layout)
+		drawText(img, x-len(label)*7/2, plot.Max.Y+15, label, graphText)
+	}
+}
+
+// drawArea fills the area under the peak players of every column with translucent color
+func drawArea(img *image.RGBA, plot image.Rectangle, cols []graphColumn, scale int, c color.RGBA) {
+	for x, col := range cols {
+		if col.up == 0 {
+			continue
+		}
+
+		top := plotY(plot, col.peak, scale)
+		for y := top; y < plot.Max.Y; y++ {
+			blend(img, plot.Min.X+x, y, c, 0x40)
+		}
+	}
+}
+
+// drawLine draws the line of average players through columns with online samples
+func drawLine(img *image.RGBA, plot image.Rectangle, cols []graphColumn, scale int, c color.RGBA) {
+	prevX, prevY := -1, 0
+	var prevTime time.Time
+
+	for x, col := range cols {
+		switch {
+		case col.offline:
+			prevX = -1
+			continue
+		case col.up == 0:
+			continue
+		}
+
+		y := plotY(plot, (col.sum+col.up/2)/col.up, scale)
+		px := plot.Min.X + x
+		if prevX >= 0 && col.time.Sub(prevTime) <= graphMaxGap {
+			strokeLine(img, prevX, prevY, px, y, c)
+		} else {
+			strokeLine(img, px, y, px, y, c)
+		}
+
+		prevX, prevY, prevTime = px, y, col.time
+	}
+}
+
+// drawOffline marks columns with only offline samples under the time axis
+func drawOffline(img *image.RGBA, plot image.Rectangle, cols []graphColumn) {
+	for x, col := range cols {
+		if col.offline {
+			fillRect(img, image.Rect(plot.Min.X+x, plot.Max.Y, plot.Min.X+x+1, plot.Max.Y+3), graphOffline)
+		}
+	}
+}
+
+// drawLegend draws the color and the name of the series in the top right corner
+func drawLegend(img *image.RGBA, i int, name string, c color.RGBA) {
+	const width = 120
+	x := graphWidth - graphRight - width*(i%4+1)
+	y := 4 + 12*(i/4)
+	if len(name) > 14 {
+		name = name[:12] + ".."
+	}
+
+	fillRect(img, image.Rect(x, y+3, x+8, y+11), c)
+	drawText(img, x+12, y+11, name, graphText)
+}
+
+// plotY returns the vertical position of the number of players
+func plotY(plot image.Rectangle, players, scale int) int {
+	if scale <= 0 {
+		return plot.Max.Y - 1
+	}
+
+	y := plot.Max.Y - 1 - (plot.Dy()-1)*min(players, scale)/scale
+	return max(y, plot.Min.Y)
+}
+
+// strokeLine draws a two pixels thick line with the Bresenham algorithm
+func strokeLine(img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := sign(x1-x0), sign(y1-y0)
+	e := dx + dy
+
+	for {
+		img.SetRGBA(x0, y0, c)
+		img.SetRGBA(x0, y0-1, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+
+		if e2 := 2 * e; e2 >= dy {
+			e += dy
+			x0 += sx
+		} else {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+// fillRect fills the rectangle with the color
+func fillRect(img *image.RGBA, r image.Rectangle, c color.RGBA) {
+	r = r.Intersect(img.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+// blend mixes the color with the pixel using the alpha from 0 to 255
+func blend(img *image.RGBA, x, y int, c color.RGBA, alpha uint8) {
+	bg := img.RGBAAt(x, y)
+	mix := func(a, b uint8) uint8 {
+		return uint8((uint16(a)*uint16(alpha) + uint16(b)*(255-uint16(alpha))) / 255) // #nosec G115
+	}
+
+	img.SetRGBA(x, y, color.RGBA{mix(c.R, bg.R), mix(c.G, bg.G), mix(c.B, bg.B), 0xFF})
+}
+
+// drawText draws the ASCII text with the baseline at the position using the built-in 7x13 font
+func drawText(img *image.RGBA, x, y int, text string, c color.RGBA) {
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(text)
+}
+
+// abs returns the absolute value
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+
+	return v
+}
+
+// sign returns -1, 0 or 1 by the sign of the value
+func sign(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"image/png"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// discordRecorder records the requests of the Discord session and answers them with an empty object
+type discordRecorder struct {
+	requests []*http.Request
+	bodies   []string
+}
+
+func (r *discordRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, _ := io.ReadAll(req.Body)
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, string(body))
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader("{}")),
+		Request:    req,
+	}, nil
+}
+
+// recordedSession returns the Discord session with requests recorded instead of sent
+func recordedSession(t *testing.T) (*discordgo.Session, *discordRecorder) {
+	t.Helper()
+
+	ds, err := discordgo.New("Bot x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := &discordRecorder{}
+	ds.Client = &http.Client{Transport: rec}
+
+	return ds, rec
+}
+
+func TestRespondGraph(t *testing.T) {
+	cfg := &Config{Servers: []ServerConfig{{ID: "srv"}}}
+	cfg.History.Windows = []string{"24h"}
+	i := &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{ID: "1", AppID: "2", Token: "t"}}
+
+	// The interaction is deferred before rendering, the graph is attached to the edited response
+	ds, rec := recordedSession(t)
+	respondGraph(ds, i, cfg, "", "")
+	if len(rec.requests) != 2 {
+		t.Fatalf("%d request(s), expected 2", len(rec.requests))
+	}
+	var deferred discordgo.InteractionResponse
+	if err := json.Unmarshal([]byte(rec.bodies[0]), &deferred); err != nil || deferred.Type != discordgo.InteractionResponseDeferredChannelMessageWithSource {
+		t.Errorf("first response = %s, %v", rec.bodies[0], err)
+	}
+	if req := rec.requests[1]; req.Method != http.MethodPatch || !strings.HasSuffix(req.URL.Path, "/webhooks/2/t/messages/@original") {
+		t.Errorf("second request = %s %s", req.Method, req.URL)
+	}
+	if !strings.Contains(rec.bodies[1], `filename="`+graphFile+`"`) || !strings.Contains(rec.bodies[1], "attachment://"+graphFile) {
+		t.Errorf("edit has no graph attached:\n%.300s", rec.bodies[1])
+	}
+
+	// Errors found before rendering are answered at once
+	for _, tt := range []struct{ id, rangeName string }{{"missing", ""}, {"srv", "7d"}} {
+		ds, rec := recordedSession(t)
+		respondGraph(ds, i, cfg, tt.id, tt.rangeName)
+		if len(rec.requests) != 1 || !strings.Contains(rec.bodies[0], `"type":4`) {
+			t.Errorf("%s %s: requests %q", tt.id, tt.rangeName, rec.bodies)
+		}
+	}
+}
+
+func TestGraphRanges(t *testing.T) {
+	if names := graphRangeNames(); !slices.Equal(names, []string{"24h", "7d"}) {
+		t.Errorf("ranges = %q, expected sorted by duration", names)
+	}
+	if choices := graphRangeChoices(); len(choices) != 2 || choices[1].Value != "7d" {
+		t.Errorf("choices = %+v", choices)
+	}
+
+	for name, valid := range map[string]bool{"": true, "24h": true, "7d": true, "1h": false} {
+		if err := validGraphRange(name); (err == nil) != valid {
+			t.Errorf("range %q: error = %v", name, err)
+		}
+	}
+	if _, err := serverGraph([]string{"srv"}, "1h"); err == nil {
+		t.Error("expected error for unknown range")
+	}
+}
+
+func TestGraphColumns(t *testing.T) {
+	from := time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+	samples := []Sample{
+		{Time: from.Add(-time.Minute), Online: true, Players: 99}, // Before the range
+		{Time: from, Online: true, Players: 10},
+		{Time: from.Add(time.Minute), Online: true, Players: 20},
+		{Time: from.Add(time.Hour), Players: 0},
+		{Time: from.Add(2 * time.Hour), Online: true, Players: 6, Peak: 9, Count: 3, Up: 2},
+	}
+
+	cols := graphColumns(samples, from, 4*time.Hour, 4)
+	if c := cols[0]; c.sum != 30 || c.up != 2 || c.peak != 20 || c.offline {
+		t.Errorf("column 0 = %+v", c)
+	}
+	if c := cols[1]; !c.filled || !c.offline {
+		t.Errorf("column 1 = %+v, expected offline", c)
+	}
+	if c := cols[2]; c.sum != 12 || c.up != 2 || c.peak != 9 {
+		t.Errorf("column 2 = %+v, expected downsampled sample", c)
+	}
+	if cols[3].filled {
+		t.Error("column 3 without samples is filled")
+	}
+}
+
+func TestNiceScale(t *testing.T) {
+	for v, want := range map[int]int{0: 4, 3: 4, 10: 12, 60: 60, 61: 80, 127: 160, 5000: 5000, 5001: 6000} {
+		if got := niceScale(v); got != want {
+			t.Errorf("scale of %d = %d, expected %d", v, got, want)
+		}
+	}
+}
+
+func TestRenderGraph(t *testing.T) {
+	to := time.Now()
+	from := to.Add(-time.Hour)
+	series := []graphSeries{
+		{name: "a", samples: []Sample{{Time: from.Add(time.Minute), Online: true, Players: 5}, {Time: to, Online: true, Players: 7}}},
+		{name: "b"},
+	}
+
+	data, err := renderGraph("Players", series, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != graphWidth || b.Dy() != graphHeight {
+		t.Errorf("image size = %s", b)
+	}
+}
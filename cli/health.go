@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Statuses of health checks
+const (
+	healthOK   = "ok"
+	healthFail = "fail"
+)
+
+/*
+Health holds the state used by the liveness and readiness probes.
+
+Liveness reflects the main loop still ticking, readiness reflects
+the Discord session being connected and at least one completed update.
+*/
+type Health struct {
+	started    time.Time     // Time the bot started
+	lastTick   time.Time     // Time of the last main loop tick
+	lastUpdate time.Time     // Time of the last completed update
+	interval   time.Duration // Expected interval between ticks
+	mu         sync.RWMutex
+	connected  bool // Discord session received Ready and is not disconnected
+}
+
+// HealthCheck is the result of a single health check
+type HealthCheck struct {
+	Time    *time.Time `json:"time,omitempty"`    // Time of the last event the check relies on
+	Status  string     `json:"status"`            // Check status "ok" or "fail"
+	Message string     `json:"message,omitempty"` // Human readable description
+}
+
+// HealthResponse is the JSON body of the health endpoints
+type HealthResponse struct {
+	Checks map[string]HealthCheck `json:"checks"` // Results of every check
+	Status string                 `json:"status"` // Overall status "ok" or "fail"
+}
+
+// health is the global state of the bot health
+var health = &Health{started: time.Now()}
+
+// setInterval sets the expected interval between main loop ticks
+func (h *Health) setInterval(interval time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.interval = interval
+}
+
+// tick marks the main loop as alive
+func (h *Health) tick() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastTick = time.Now()
+}
+
+// updated marks an update as completed
+func (h *Health) updated() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastUpdate = time.Now()
+}
+
+// setConnected sets the state of the Discord session
+func (h *Health) setConnected(connected bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.connected = connected
+}
+
+/*
+liveness checks the main loop ticked within three update intervals.
+
+Before the first tick (while connecting to Discord) the start time is used.
+*/
+func (h *Health) liveness() HealthResponse {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	last := h.lastTick
+	if last.IsZero() {
+		last = h.started
+	}
+
+	check := HealthCheck{Status: healthOK, Time: &last, Message: "main loop is ticking"}
+	if h.interval > 0 && time.Since(last) > 3*h.interval {
+		check.Status = healthFail
+		check.Message = "main loop has not ticked for " + time.Since(last).Truncate(time.Second).String()
+	}
+
+	return newHealthResponse(map[string]HealthCheck{"main_loop": check})
+}
+
+// readiness checks the Discord session is connected and at least one update completed
+func (h *Health) readiness() HealthResponse {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	discord := HealthCheck{Status: healthOK, Message: "session is connected"}
+	if !h.connected {
+		discord.Status = healthFail
+		discord.Message = "session is not connected"
+	}
+
+	update := HealthCheck{Status: healthOK, Message: "update completed"}
+	if h.lastUpdate.IsZero() {
+		update.Status = healthFail
+		update.Message = "no update completed yet"
+	} else {
+		last := h.lastUpdate
+		update.Time = &last
+	}
+
+	return newHealthResponse(map[string]HealthCheck{"discord": discord, "update": update})
+}
+
+// newHealthResponse creates the response with overall status failed if any check failed
+func newHealthResponse(checks map[string]HealthCheck) HealthResponse {
+	resp := HealthResponse{Status: healthOK, Checks: checks}
+	for _, check := range checks {
+		if check.Status != healthOK {
+			resp.Status = healthFail
+		}
+	}
+
+	return resp
+}
+
+// healthHandler returns an HTTP handler writing the check result as JSON, 503 if check failed
+func healthHandler(check func() HealthResponse) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		resp := check()
+
+		w.Header().Set("Content-Type", "application/json")
+		if resp.Status != healthOK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			log.Error().Err(err).Msg("Error writing health response")
+		}
+	}
+}
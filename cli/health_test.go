@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealthLiveness(t *testing.T) {
+	h := &Health{started: time.Now(), interval: time.Minute}
+
+	if resp := h.liveness(); resp.Status != healthOK {
+		t.Errorf("liveness before the first tick = %+v", resp)
+	}
+
+	// Main loop stuck for more than three intervals
+	h.lastTick = time.Now().Add(-4 * time.Minute)
+	if resp := h.liveness(); resp.Status != healthFail || resp.Checks["main_loop"].Status != healthFail {
+		t.Errorf("liveness of stuck loop = %+v", resp)
+	}
+
+	h.tick()
+	if resp := h.liveness(); resp.Status != healthOK {
+		t.Errorf("liveness after tick = %+v", resp)
+	}
+}
+
+func TestHealthReadiness(t *testing.T) {
+	h := &Health{started: time.Now()}
+
+	resp := h.readiness()
+	if resp.Status != healthFail || resp.Checks["discord"].Status != healthFail || resp.Checks["update"].Status != healthFail {
+		t.Errorf("readiness on start = %+v", resp)
+	}
+
+	h.setConnected(true)
+	if resp = h.readiness(); resp.Status != healthFail || resp.Checks["discord"].Status != healthOK {
+		t.Errorf("readiness without update = %+v", resp)
+	}
+
+	h.updated()
+	if resp = h.readiness(); resp.Status != healthOK || resp.Checks["update"].Time == nil {
+		t.Errorf("readiness after update = %+v", resp)
+	}
+
+	h.setConnected(false)
+	if resp = h.readiness(); resp.Status != healthFail {
+		t.Errorf("readiness after disconnect = %+v", resp)
+	}
+}
+
+func TestHealthHandler(t *testing.T) {
+	tests := []struct {
+		status string
+		code   int
+	}{
+		{healthOK, http.StatusOK},
+		{healthFail, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		handler := healthHandler(func() HealthResponse {
+			return newHealthResponse(map[string]HealthCheck{"check": {Status: tt.status}})
+		})
+
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/health/readiness", nil))
+
+		if rec.Code != tt.code {
+			t.Errorf("%s: code = %d, expected %d", tt.status, rec.Code, tt.code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s: content type = %q", tt.status, ct)
+		}
+
+		var resp HealthResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Status != tt.status || resp.Checks["check"].Status != tt.status {
+			t.Errorf("%s: body = %+v", tt.status, resp)
+		}
+	}
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// historyToday is the window name of the statistics since local midnight
+const historyToday = "today"
+
+/*
+HistoryConfig represents the configuration of the history of server samples.
+
+Recent samples are kept in memory for .History in templates, with data_dir
+all samples are also stored on disk and downsampled after raw_retention.
+*/
+type HistoryConfig struct {
+	Windows      []string      `yaml:"windows,omitempty" default:"[1h,24h,today]"` // Windows of peak/min/avg statistics, durations or "today"
+	DataDir      string        `yaml:"data_dir,omitempty"`                         // Directory to store samples, disabled if empty
+	Retention    time.Duration `yaml:"retention,omitempty" default:"720h"`         // How long stored samples are kept
+	RawRetention time.Duration `yaml:"raw_retention,omitempty" default:"48h"`      // How long stored samples are kept without downsampling
+	Resolution   time.Duration `yaml:"resolution,omitempty" default:"10m"`         // Interval of downsampled samples
+}
+
+/*
+Sample is the result of one server query or the aggregate of several downsampled ones.
+
+Samples are collected on every update for the history of the server.
+*/
+type Sample struct {
+	Time       time.Time     `json:"time"`                  // Time of the query, start of the interval if downsampled
+	Map        string        `json:"map,omitempty"`         // Map name, empty if offline
+	Latency    time.Duration `json:"latency,omitempty"`     // Latency of A2S_INFO, zero if offline, average if downsampled
+	Players    int           `json:"players"`               // Number of players, average if downsampled
+	Peak       int           `json:"peak,omitempty"`        // Maximum number of players if downsampled
+	MaxPlayers int           `json:"max_players,omitempty"` // Number of slots
+	Queue      int           `json:"queue,omitempty"`       // Number of players in the queue (DayZ), maximum if downsampled
+	Count      int           `json:"count,omitempty"`       // Number of downsampled samples, zero for a single query
+	Up         int           `json:"up,omitempty"`          // Number of downsampled online samples
+	Online     bool          `json:"online"`                // Server responded to A2S_INFO, to any query if downsampled
+}
+
+/*
+History represents the historical data of the server passed to templates as .History.
+
+Statistics of windows are calculated from online samples only, windows are named
+as in the history.windows option, e.g. {{ .History.Peak "24h" }}.
+*/
+type History struct {
+	Windows   map[string]*HistoryWindow // Statistics by window name
+	Previous  Sample                    // Sample of the previous update, zero before the second update
+	UpSince   time.Time                 // Time the server is online since, zero if offline
+	OnlineFor time.Duration             // Duration the server is online since the last outage or bot start
+}
+
+// HistoryWindow represents the statistics of players within the window
+type HistoryWindow struct {
+	PeakTime time.Time // Time of the peak
+	Avg      float64   // Average number of players
+	Peak     int       // Maximum number of players
+	Min      int       // Minimum number of players
+	Samples  int       // Number of online samples
+}
+
+// HistoryStore keeps the recent samples of every server in memory
+type HistoryStore struct {
+	servers map[string]*serverHistory
+	mu      sync.Mutex
+}
+
+// serverHistory is the history of one server
+type serverHistory struct {
+	upSince  time.Time // Time the server is online since
+	samples  []Sample  // Samples within the retention, oldest first
+	failures int       // Number of consecutive failed queries
+}
+
+// history holds the recent samples of all servers
+var history = &HistoryStore{servers: make(map[string]*serverHistory)}
+
+// validate checks the history windows and store durations
+func (c *HistoryConfig) validate() error {
+	for _, w := range c.Windows {
+		if _, err := parseWindow(w); err != nil {
+			return err
+		}
+	}
+
+	if c.DataDir == "" {
+		return nil
+	}
+	if c.Resolution <= 0 {
+		return fmt.Errorf("history resolution must be positive")
+	}
+	if c.RawRetention < 0 || c.Retention < c.RawRetention {
+		return fmt.Errorf("history retention %s must not be shorter than raw_retention %s", c.Retention, c.RawRetention)
+	}
+
+	return nil
+}
+
+// retention returns the longest window, samples older than it are dropped
+func (c *HistoryConfig) retention() time.Duration {
+	var longest time.Duration
+	for _, w := range c.Windows {
+		d, _ := parseWindow(w)
+		longest = max(longest, d)
+	}
+
+	return longest
+}
+
+// keeps reports whether samples of the period ending now are kept, without data_dir only the retention is kept in memory
+func (c *HistoryConfig) keeps(period time.Duration) bool {
+	return c.DataDir != "" || period <= c.retention()
+}
+
+// empty returns the history without samples with all configured windows
+func (c *HistoryConfig) empty() *History {
+	h := &History{Windows: make(map[string]*HistoryWindow, len(c.Windows))}
+	for _, w := range c.Windows {
+		h.Windows[w] = &HistoryWindow{}
+	}
+
+	return h
+}
+
+// parseWindow returns the duration of the window, "today" is 24 hours long at most
+func parseWindow(w string) (time.Duration, error) {
+	if w == historyToday {
+		return 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(w)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid history window %q, expected positive duration like 24h or %q", w, historyToday)
+	}
+
+	return d, nil
+}
+
+// windowStart returns the start time of the window ending now
+func windowStart(w string, now time.Time) time.Time {
+	if w == historyToday {
+		y, m, d := now.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	}
+
+	d, _ := parseWindow(w)
+	return now.Add(-d)
+}
+
+// newSample creates the sample from the query result
+func newSample(tpl *TemplateData, queue int, now time.Time) Sample {
+	s := Sample{Time: now}
+	if tpl.Info == nil {
+		return s
+	}
+
+	s.Online = true
+	s.Map = tpl.Info.Map
+	s.Latency = tpl.Info.Ping
+	s.Players = int(tpl.Info.Players)
+	s.MaxPlayers = int(tpl.Info.MaxPlayers)
+	s.Queue = queue
+
+	return s
+}
+
+/*
+record adds the sample to the history of the server and returns the history for templates.
+
+Samples older than the longest window are dropped. UpSince is reset after the number
+of consecutive failed queries configured in alerts.failures, the same way as alerts
+declare an outage.
+*/
+func (h *HistoryStore) record(id string, s Sample, cfg *Config) *History {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sh := h.server(id)
+
+	result := cfg.History.empty()
+	if n := len(sh.samples); n > 0 {
+		result.Previous = sh.samples[n-1]
+	}
+
+	sh.add(s, cfg)
+
+	for name, w := range result.Windows {
+		w.calculate(sh.samples, windowStart(name, s.Time))
+	}
+
+	if !sh.upSince.IsZero() && s.Online {
+		result.UpSince = sh.upSince
+		result.OnlineFor = s.Time.Sub(sh.upSince).Truncate(time.Second)
+	}
+
+	return result
+}
+
+/*
+seed fills the history of all servers from the stored samples.
+
+It is used at startup, so statistics and uptime continue after restart. If the
+bot was stopped for longer than alerts.failures update intervals, the server
+could have been down unnoticed, so the uptime starts again from the next query.
+*/
+func (h *HistoryStore) seed(cfg *Config, store *SampleStore) {
+	if store == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	since := time.Now().Add(-cfg.History.retention())
+	for _, srv := range cfg.Servers {
+		samples, err := store.read(srv.ID, since)
+		if err != nil {
+			log.Error().Err(err).Str("server", srv.ID).Msg("Failed to read stored samples")
+			continue
+		}
+
+		sh := h.server(srv.ID)
+		for _, s := range samples {
+			sh.add(s, cfg)
+		}
+
+		gap := time.Duration(cfg.Alerts.Failures) * cfg.Bot.UpdateInterval
+		if len(samples) > 0 && time.Since(samples[len(samples)-1].Time) > gap {
+			sh.upSince = time.Time{}
+		}
+	}
+}
+
+// server returns the history of the server, creating it if needed, must be called with lock held
+func (h *HistoryStore) server(id string) *serverHistory {
+	sh, ok := h.servers[id]
+	if !ok {
+		sh = &serverHistory{}
+		h.servers[id] = sh
+	}
+
+	return sh
+}
+
+// add appends the sample, drops samples older than the longest window and tracks the uptime
+func (sh *serverHistory) add(s Sample, cfg *Config) {
+	if s.Online {
+		sh.failures = 0
+		if sh.upSince.IsZero() {
+			sh.upSince = s.Time
+		}
+	} else {
+		sh.failures += s.count()
+		if sh.failures >= cfg.Alerts.Failures {
+			sh.upSince = time.Time{}
+		}
+	}
+
+	cutoff := s.Time.Add(-cfg.History.retention())
+	drop := 0
+	for drop < len(sh.samples) && sh.samples[drop].Time.Before(cutoff) {
+		drop++
+	}
+	sh.samples = append(slices.Delete(sh.samples, 0, drop), s)
+}
+
+// delete removes the history of the server removed from configuration
+func (h *HistoryStore) delete(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.servers, id)
+}
+
+// calculate fills the statistics from online samples since the start time
+func (w *HistoryWindow) calculate(samples []Sample, start time.Time) {
+	var sum int
+	for _, s := range samples {
+		if !s.Online || s.Time.Before(start) {
+			continue
+		}
+
+		if w.Samples == 0 || s.Players < w.Min {
+			w.Min = s.Players
+		}
+		if peak := s.peak(); w.Samples == 0 || peak > w.Peak {
+			w.Peak = peak
+			w.PeakTime = s.Time
+		}
+		up := s.up()
+		sum += s.Players * up
+		w.Samples += up
+	}
+
+	if w.Samples > 0 {
+		w.Avg = float64(sum) / float64(w.Samples)
+	}
+}
+
+// peak returns the maximum number of players of the sample
+func (s *Sample) peak() int {
+	if s.Count > 0 {
+		return s.Peak
+	}
+
+	return s.Players
+}
+
+// count returns the number of queries of the sample
+func (s *Sample) count() int {
+	return max(s.Count, 1)
+}
+
+// up returns the number of online queries of the sample
+func (s *Sample) up() int {
+	switch {
+	case s.Count > 0:
+		return s.Up
+	case s.Online:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Window returns the statistics of the window by name from history.windows
+func (h *History) Window(name string) (*HistoryWindow, error) {
+	w, ok := h.Windows[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown history window %q, add it to history.windows", name)
+	}
+
+	return w, nil
+}
+
+// Peak returns the maximum number of players within the window
+func (h *History) Peak(name string) (int, error) {
+	w, err := h.Window(name)
+	if err != nil {
+		return 0, err
+	}
+
+	return w.Peak, nil
+}
+
+// Min returns the minimum number of players within the window
+func (h *History) Min(name string) (int, error) {
+	w, err := h.Window(name)
+	if err != nil {
+		return 0, err
+	}
+
+	return w.Min, nil
+}
+
+// Avg returns the average number of players within the window
+func (h *History) Avg(name string) (float64, error) {
+	w, err := h.Window(name)
+	if err != nil {
+		return 0, err
+	}
+
+	return w.Avg, nil
+}
+
+// tplHelperTrend returns an arrow showing whether the value went up, down or stayed the same
+func tplHelperTrend(cur, prev any) string {
+	c, p := toInt64(cur), toInt64(prev)
+	switch {
+	case c > p:
+		return "↑"
+	case c < p:
+		return "↓"
+	default:
+		return "→"
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/woozymasta/a2s/pkg/a2s"
+)
+
+// historyConfig returns the configuration with the history windows and failures of alerts
+func historyConfig(failures int, windows ...string) *Config {
+	cfg := &Config{Alerts: Alerts{Failures: failures}}
+	cfg.History.Windows = windows
+	return cfg
+}
+
+func TestHistoryRecord(t *testing.T) {
+	h := &HistoryStore{servers: make(map[string]*serverHistory)}
+	cfg := historyConfig(2, "1h", "24h")
+	start := time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+	// Players every 30 minutes: the first sample is outside of the 1h window
+	players := []int{50, 10, 20, 30}
+	var got *History
+	for i, n := range players {
+		at := start.Add(time.Duration(i) * 30 * time.Minute)
+		got = h.record("srv", Sample{Time: at, Online: true, Players: n}, cfg)
+	}
+
+	hour, day := got.Windows["1h"], got.Windows["24h"]
+	if hour.Peak != 30 || hour.Min != 10 || hour.Avg != 20 || hour.Samples != 3 {
+		t.Errorf("1h = %+v", hour)
+	}
+	if day.Peak != 50 || !day.PeakTime.Equal(start) || day.Samples != 4 || day.Avg != 27.5 {
+		t.Errorf("24h = %+v", day)
+	}
+	if got.Previous.Players != 20 {
+		t.Errorf("previous = %+v", got.Previous)
+	}
+	if !got.UpSince.Equal(start) || got.OnlineFor != 90*time.Minute {
+		t.Errorf("up since %s for %s", got.UpSince, got.OnlineFor)
+	}
+}
+
+func TestHistoryUpSince(t *testing.T) {
+	h := &HistoryStore{servers: make(map[string]*serverHistory)}
+	cfg := historyConfig(2, "1h")
+	at := time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		online bool
+		since  time.Duration // Expected UpSince after the start, -1 for zero
+	}{
+		{true, 0},
+		{false, -1}, // Offline sample has no uptime
+		{true, 0},   // One failure is below alerts.failures
+		{false, -1},
+		{false, -1}, // Second failure in a row is an outage
+		{true, 5 * time.Minute},
+	}
+
+	for i, st := range steps {
+		got := h.record("srv", Sample{Time: at.Add(time.Duration(i) * time.Minute), Online: st.online}, cfg)
+
+		want := time.Time{}
+		if st.since >= 0 {
+			want = at.Add(st.since)
+		}
+		if !got.UpSince.Equal(want) {
+			t.Errorf("step %d: up since %s, expected %s", i, got.UpSince, want)
+		}
+	}
+}
+
+func TestHistoryRetention(t *testing.T) {
+	h := &HistoryStore{servers: make(map[string]*serverHistory)}
+	cfg := historyConfig(1, "1h", historyToday)
+	at := time.Date(2025, time.June, 1, 23, 0, 0, 0, time.UTC)
+
+	h.record("srv", Sample{Time: at.Add(-25 * time.Hour), Online: true, Players: 99}, cfg)
+	h.record("srv", Sample{Time: at.Add(-2 * time.Hour), Online: true, Players: 40}, cfg)
+	got := h.record("srv", Sample{Time: at, Online: true, Players: 5}, cfg)
+
+	// Samples older than the longest window are dropped, "today" starts at midnight
+	if n := len(h.servers["srv"].samples); n != 2 {
+		t.Errorf("samples = %d, expected 2", n)
+	}
+	if today := got.Windows[historyToday]; today.Peak != 40 || today.Samples != 2 {
+		t.Errorf("today = %+v", today)
+	}
+	if hour := got.Windows["1h"]; hour.Peak != 5 || hour.Samples != 1 {
+		t.Errorf("1h = %+v", hour)
+	}
+
+	h.delete("srv")
+	if _, ok := h.servers["srv"]; ok {
+		t.Error("history of deleted server is kept")
+	}
+}
+
+func TestNewSample(t *testing.T) {
+	at := time.Now()
+
+	s := newSample(&TemplateData{Info: &a2s.Info{Map: "livonia", Players: 3, MaxPlayers: 60, Ping: time.Millisecond}}, 2, at)
+	want := Sample{Time: at, Online: true, Map: "livonia", Players: 3, MaxPlayers: 60, Latency: time.Millisecond, Queue: 2}
+	if s != want {
+		t.Errorf("sample = %+v, expected %+v", s, want)
+	}
+
+	if s = newSample(&TemplateData{}, 2, at); s != (Sample{Time: at}) {
+		t.Errorf("offline sample = %+v", s)
+	}
+}
+
+func TestHistoryConfigValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  HistoryConfig
+		err  bool
+	}{
+		{"windows", HistoryConfig{Windows: []string{"1h", historyToday}}, false},
+		{"invalid window", HistoryConfig{Windows: []string{"day"}}, true},
+		{"negative window", HistoryConfig{Windows: []string{"-1h"}}, true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.cfg.validate(); (err != nil) != tt.err {
+			t.Errorf("%s: error = %v", tt.name, err)
+		}
+	}
+}
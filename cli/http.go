@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
+)
+
+/*
+HTTP represents the configuration of the built-in HTTP server.
+
+The server is started only if the listen address is set.
+*/
+type HTTP struct {
+	Listen string `yaml:"listen,omitempty"` // Listen address (e.g. ":8080"), not set to disable
+}
+
+/*
+startHTTPServer starts the HTTP server with the /metrics and /health/* endpoints in background.
+
+Returns nil if the listen address is not configured.
+*/
+func (h *HTTP) startHTTPServer() *http.Server {
+	if h.Listen == "" {
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/health/liveness", healthHandler(health.liveness))
+	mux.Handle("/health/readiness", healthHandler(health.readiness))
+
+	srv := &http.Server{
+		Addr:              h.Listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		log.Info().Str("listen", h.Listen).Msg("Starting HTTP server")
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal().Err(err).Str("listen", h.Listen).Msg("Error starting HTTP server")
+		}
+	}()
+
+	return srv
+}
+
+// stopHTTPServer gracefully shuts down the HTTP server
+func stopHTTPServer(srv *http.Server) {
+	if srv == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Error().Err(err).Msg("Error shutting down HTTP server")
+	}
+}
@@ -46,26 +46,85 @@ func runApp() {
 	if err != nil {
 		log.Fatal().Err(err).Msg("Error reading configuration")
 	}
+	activeConfig.Store(cfg)
+
+	// Restore the persisted state to avoid re-editing unchanged channels after restart.
+	if cfg.Bot.StateFile != "" {
+		botState, err = loadState(cfg.Bot.StateFile)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Error loading state")
+		}
+		botState.restore(cfg)
+		defer func() {
+			if err := botState.save(); err != nil {
+				log.Error().Err(err).Msg("Error saving state")
+			}
+		}()
+	}
+
+	// Open the sample store and continue the history of servers from it.
+	if cfg.History.DataDir != "" {
+		sampleStore, err = openSampleStore(&cfg.History)
+		if err != nil {
+			fatal().Err(err).Msg("Error opening sample store")
+		}
+		history.seed(cfg, sampleStore)
+		uptimes.seed(cfg, sampleStore)
+	}
+
+	// Start the HTTP server for metrics and health checks if configured.
+	health.setInterval(cfg.Bot.UpdateInterval)
+	httpServer := cfg.HTTP.startHTTPServer()
+	defer stopHTTPServer(httpServer)
 
 	// Create a new Discord session using the bot token from the configuration.
 	dg, err := discordgo.New("Bot " + cfg.Bot.Token)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Error creating Discord session")
+		fatal().Err(err).Msg("Error creating Discord session")
 	}
 
 	// Channel to wait for the Ready event.
 	ready := make(chan struct{})
+	var readyOnce sync.Once
+
+	// Changing no_commands requires restart, copy it as cfg is replaced on reload.
+	noCommands := cfg.Bot.NoCommands
 
-	// Add a handler for the Ready event.
-	dg.AddHandler(func(_ *discordgo.Session, r *discordgo.Ready) {
+	// Add a handler for the Ready event, it can be received again after reconnect.
+	dg.AddHandler(func(s *discordgo.Session, r *discordgo.Ready) {
 		log.Debug().Msgf("Bot session %s opened", r.SessionID)
-		close(ready)
+
+		if !noCommands {
+			if err := registerCommands(s); err != nil {
+				log.Error().Err(err).Msg("Error registering application commands")
+			}
+		}
+
+		health.setConnected(true)
+		readyOnce.Do(func() { close(ready) })
 	})
 
+	// Track the state of the Discord session for the readiness probe.
+	dg.AddHandler(func(_ *discordgo.Session, _ *discordgo.Disconnect) {
+		log.Warn().Msg("Bot session disconnected")
+		health.setConnected(false)
+	})
+	dg.AddHandler(func(_ *discordgo.Session, _ *discordgo.Resumed) {
+		log.Debug().Msg("Bot session resumed")
+		health.setConnected(true)
+	})
+
+	// Add a handler for slash commands, answers are built from cached data.
+	if !noCommands {
+		dg.AddHandler(func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+			handleInteraction(s, i, activeConfig.Load())
+		})
+	}
+
 	// Open a websocket connection to Discord and begin listening.
 	err = dg.Open()
 	if err != nil {
-		log.Fatal().Err(err).Msg("Error opening Discord session")
+		fatal().Err(err).Msg("Error opening Discord session")
 	}
 	defer func() {
 		if err := dg.Close(); err != nil {
@@ -80,7 +139,34 @@ func runApp() {
 
 	// --- Start worker pool for async channel/category updates ---
 	// Use concurrency from config, and some timeout for blocking calls (e.g. 30s).
-	startUpdateWorkers(dg, cfg.Bot.Concurrency, 30*time.Second)
+	startUpdateWorkers(dg, cfg.Bot.Concurrency, discordTimeout)
+
+	// Open sessions of additional bots bound to their servers.
+	sessions, err := openBots(cfg)
+	defer func() {
+		for _, ds := range sessions {
+			if err := ds.Close(); err != nil {
+				log.Error().Err(err).Msg("Error close Discord websocket connection of additional bot")
+			}
+		}
+	}()
+	if err != nil {
+		fatal().Err(err).Msg("Error opening Discord session of additional bot")
+	}
+
+	presenceBots = append(presenceBots, newPresenceBot(dg, -1))
+	for i, ds := range sessions {
+		presenceBots = append(presenceBots, newPresenceBot(ds, i))
+	}
+
+	// Rotate Rich Presence on its own interval if configured, using cached results.
+	for _, b := range presenceBots {
+		go b.rotator.run(b)
+	}
+
+	// Post scheduled reports and collected player events of the active configuration.
+	go runReports(dg)
+	go playerEvents.run(dg)
 
 	// Create a ticker that triggers at intervals specified in the configuration.
 	ticker := time.NewTicker(cfg.Bot.UpdateInterval)
@@ -90,18 +176,32 @@ func runApp() {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 
+	// Channels to reload configuration on SIGHUP or configuration file change.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	path, _ := configPath()
+	changed := watchConfig(path)
+
 	// Perform an initial update before entering the update loop.
+	health.tick()
 	update(dg, cfg)
 
 	// WaitGroup to ensure all goroutines finish before exiting.
 	var wg sync.WaitGroup
 	wg.Add(1)
 
-	// Main loop: every tick we call update(), or stop if SIGINT/SIGTERM
+	// Main loop: every tick we call update(), reload on SIGHUP or file change, or stop if SIGINT/SIGTERM
 	for {
 		select {
 		case <-ticker.C:
+			health.tick()
 			update(dg, cfg)
+		case <-hup:
+			log.Info().Msg("SIGHUP received, reloading configuration")
+			cfg = reload(dg, cfg, ticker)
+		case <-changed:
+			log.Info().Msg("Configuration or template file changed, reloading configuration")
+			cfg = reload(dg, cfg, ticker)
 		case <-stop:
 			// Received a termination signal, initiate shutdown.
 			log.Info().Msg("Termination signal received. Stopping the bot...")
@@ -110,3 +210,25 @@ func runApp() {
 		}
 	}
 }
+
+/*
+reload applies the new configuration and performs an update with it.
+
+If the new configuration is invalid, the error is logged and the current one is returned.
+*/
+func reload(ds *discordgo.Session, cfg *Config, ticker *time.Ticker) *Config {
+	newCfg, err := reloadConfig(cfg)
+	if err != nil {
+		log.Error().Err(err).Msg("Error reloading configuration, keeping the current one")
+		return cfg
+	}
+
+	activeConfig.Store(newCfg)
+	health.setInterval(newCfg.Bot.UpdateInterval)
+	ticker.Reset(newCfg.Bot.UpdateInterval)
+
+	health.tick()
+	update(ds, newCfg)
+
+	return newCfg
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+	"github.com/zeebo/xxh3"
+)
+
+// Discord embed limits
+const (
+	maxEmbedTitle       = 256
+	maxEmbedDescription = 4096
+	maxEmbedFieldName   = 256
+	maxEmbedFieldValue  = 1024
+	maxEmbedFooter      = 2048
+)
+
+// defaultGraphInterval is the interval of refreshing the graph of the status message if not set
+const defaultGraphInterval = 10 * time.Minute
+
+/*
+StatusMessage represents the configuration of an auto-updating status message.
+
+The bot posts one embed into the text channel and edits it on every update.
+If no templates are set, the built-in embed (same as for /status) is used.
+*/
+type StatusMessage struct {
+	ChannelID   string       `yaml:"channel_id"`            // Discord text channel ID to post the message
+	MessageID   string       `yaml:"message_id,omitempty"`  // Existing message ID to edit instead of posting a new one
+	Title       string       `yaml:"title,omitempty"`       // Template for embed title
+	Description string       `yaml:"description,omitempty"` // Template for embed description
+	Color       string       `yaml:"color,omitempty"`       // Template for embed color in hex (e.g. #2ECC71)
+	Footer      string       `yaml:"footer,omitempty"`      // Template for embed footer
+	Fields      []EmbedField `yaml:"fields,omitempty"`      // Templates for embed fields
+
+	TitleFile       string `yaml:"title_file,omitempty"`       // File with template for embed title
+	DescriptionFile string `yaml:"description_file,omitempty"` // File with template for embed description
+	ColorFile       string `yaml:"color_file,omitempty"`       // File with template for embed color
+	FooterFile      string `yaml:"footer_file,omitempty"`      // File with template for embed footer
+
+	Graph         string        `yaml:"graph,omitempty"`          // Range of the attached players graph (24h, 7d), disabled if empty
+	GraphInterval time.Duration `yaml:"graph_interval,omitempty"` // Interval of refreshing the graph, 10m if not set
+
+	tplTitle       *template.Template // Compiled Title
+	tplDescription *template.Template // Compiled Description
+	tplColor       *template.Template // Compiled Color
+	tplFooter      *template.Template // Compiled Footer
+
+	server   string     // Server ID, empty for the message of all servers
+	prevHash uint64     // Previous hash of the embed
+	graphAt  time.Time  // Time of the last attached graph
+	mu       sync.Mutex // Prevents concurrent edits of the same message
+}
+
+// EmbedField represents the templates for one embed field, fields rendered to empty name or value are skipped.
+type EmbedField struct {
+	Name   string `yaml:"name"`             // Template for field name
+	Value  string `yaml:"value"`            // Template for field value
+	Inline bool   `yaml:"inline,omitempty"` // Show field inline
+
+	NameFile  string `yaml:"name_file,omitempty"`  // File with template for field name
+	ValueFile string `yaml:"value_file,omitempty"` // File with template for field value
+
+	tplName  *template.Template // Compiled Name
+	tplValue *template.Template // Compiled Value
+}
+
+/*
+process renders and posts or edits the status message.
+
+If the previous update of this message is still in progress, the call is skipped.
+The fallback embed is used when no templates are configured. With graph set,
+the embed shows the attached graph of players of the servers of the data.
+*/
+func (m *StatusMessage) process(ds *discordgo.Session, data any, fallback func() *discordgo.MessageEmbed, timeout time.Duration) {
+	if m == nil || m.ChannelID == "" || ds == nil {
+		return
+	}
+
+	if !m.mu.TryLock() {
+		log.Debug().Str("channel", m.ChannelID).Msg("Skipping status message update, previous one still in progress")
+		return
+	}
+	defer m.mu.Unlock()
+
+	embed := m.render(data)
+	if embed == nil {
+		embed = fallback()
+	}
+	if m.Graph != "" {
+		embed.Image = &discordgo.MessageEmbedImage{URL: "attachment://" + graphFile}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := m.update(ctx, ds, embed, serverIDs(data)); err != nil {
+		log.Error().Err(err).Str("channel", m.ChannelID).Msg("Failed to update status message")
+	}
+}
+
+// render renders the embed templates, returns nil if no templates are configured
+func (m *StatusMessage) render(data any) *discordgo.MessageEmbed {
+	if m.Title == "" && m.Description == "" && len(m.Fields) == 0 {
+		return nil
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       m.renderField(m.tplTitle, data, "title", maxEmbedTitle),
+		Description: m.renderField(m.tplDescription, data, "description", maxEmbedDescription),
+	}
+
+	if footer := m.renderField(m.tplFooter, data, "footer", maxEmbedFooter); footer != "" {
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: footer}
+	}
+
+	if color := m.renderField(m.tplColor, data, "color", 0); color != "" {
+		value, err := parseColor(color)
+		if err != nil {
+			log.Error().Err(err).Str("channel", m.ChannelID).Msg("Error parsing status message color")
+		}
+		embed.Color = value
+	}
+
+	for _, f := range m.Fields {
+		if len(embed.Fields) >= maxEmbedFields {
+			break
+		}
+
+		name := m.renderField(f.tplName, data, "field name", maxEmbedFieldName)
+		value := m.renderField(f.tplValue, data, "field value", maxEmbedFieldValue)
+		if name == "" || value == "" {
+			continue
+		}
+
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: name, Value: value, Inline: f.Inline})
+	}
+
+	return embed
+}
+
+// renderField renders one template, trims spaces and cuts the result to the limit (0 means no limit)
+func (m *StatusMessage) renderField(tmpl *template.Template, data any, name string, limit int) string {
+	if tmpl == nil {
+		return ""
+	}
+
+	rendered, err := executeTemplate(tmpl, data)
+	if err != nil {
+		log.Error().Err(err).Str("channel", m.ChannelID).Msgf("Error rendering status message %s template", name)
+	}
+
+	rendered = strings.TrimSpace(rendered)
+	if limit > 0 {
+		rendered = truncate(rendered, limit)
+	}
+
+	return rendered
+}
+
+/*
+update compares the embed hash with the previous one and edits the message if changed.
+
+If the message is not known yet, or was deleted, a new message is posted.
+The embed timestamp is set to the time of the last change. The graph is rendered
+again and replaces the attached one on every edit, and at least every graph_interval.
+*/
+func (m *StatusMessage) update(ctx context.Context, ds *discordgo.Session, embed *discordgo.MessageEmbed, ids []string) error {
+	newHash, err := embedHash(embed)
+	if err != nil {
+		return err
+	}
+	graphDue := m.Graph != "" && time.Since(m.graphAt) >= m.graphInterval()
+	if newHash == m.prevHash && m.MessageID != "" && !graphDue {
+		log.Debug().Str("channel", m.ChannelID).Msg("Skipping status message update without changes detected")
+		return nil
+	}
+
+	embed.Timestamp = time.Now().Format(time.RFC3339)
+	files := m.graphFiles(ids)
+
+	if m.MessageID != "" {
+		edit := &discordgo.MessageEdit{ID: m.MessageID, Channel: m.ChannelID, Embeds: &[]*discordgo.MessageEmbed{embed}}
+		if files != nil {
+			edit.Files = files
+			edit.Attachments = &[]*discordgo.MessageAttachment{} // Replace the previous graph
+		}
+
+		_, err := ds.ChannelMessageEditComplex(edit, discordgo.WithContext(ctx))
+		if err == nil {
+			m.applied(newHash, files != nil)
+			return nil
+		}
+
+		var restErr *discordgo.RESTError
+		if !errors.As(err, &restErr) || restErr.Message == nil || restErr.Message.Code != discordgo.ErrCodeUnknownMessage {
+			return err
+		}
+
+		log.Warn().
+			Str("channel", m.ChannelID).
+			Str("message", m.MessageID).
+			Msg("Status message was deleted, posting a new one")
+	}
+
+	send := &discordgo.MessageSend{Embeds: []*discordgo.MessageEmbed{embed}, Files: files}
+	msg, err := ds.ChannelMessageSendComplex(m.ChannelID, send, discordgo.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("channel", m.ChannelID).
+		Str("message", msg.ID).
+		Msg("Status message posted, set message_id or bot.state_file to reuse it after restart")
+
+	m.MessageID = msg.ID
+	m.applied(newHash, files != nil)
+
+	return nil
+}
+
+// applied stores the hash of the successfully posted or edited embed and persists the message state
+func (m *StatusMessage) applied(hash uint64, graph bool) {
+	m.prevHash = hash
+	if graph {
+		m.graphAt = time.Now()
+	}
+	botState.setMessage(m.server, &MessageState{ChannelID: m.ChannelID, MessageID: m.MessageID, Hash: hash})
+}
+
+// graphFiles renders the graph of the servers as the message attachment, nil if disabled or failed
+func (m *StatusMessage) graphFiles(ids []string) []*discordgo.File {
+	if m.Graph == "" {
+		return nil
+	}
+
+	data, err := serverGraph(ids, m.Graph)
+	if err != nil {
+		log.Error().Err(err).Str("channel", m.ChannelID).Msg("Failed to render status message graph")
+		return nil
+	}
+
+	return []*discordgo.File{{Name: graphFile, ContentType: "image/png", Reader: bytes.NewReader(data)}}
+}
+
+// graphInterval returns the interval of refreshing the graph
+func (m *StatusMessage) graphInterval() time.Duration {
+	if m.GraphInterval > 0 {
+		return m.GraphInterval
+	}
+
+	return defaultGraphInterval
+}
+
+// validate checks the graph range of the status message and that its samples are kept
+func (m *StatusMessage) validate(h *HistoryConfig) error {
+	if m == nil {
+		return nil
+	}
+
+	if err := validGraphRange(m.Graph); err != nil {
+		return err
+	}
+	if m.Graph != "" && !h.keeps(graphRanges[m.Graph]) {
+		return fmt.Errorf("graph %s needs history.data_dir, the in-memory history keeps only %s", m.Graph, h.retention())
+	}
+
+	return nil
+}
+
+// serverIDs returns the IDs of the servers of the template or summary data
+func serverIDs(data any) []string {
+	switch d := data.(type) {
+	case *TemplateData:
+		return []string{d.ID}
+	case *SummaryData:
+		ids := make([]string, len(d.Servers))
+		for i, tpl := range d.Servers {
+			ids[i] = tpl.ID
+		}
+		return ids
+	default:
+		return nil
+	}
+}
+
+// embedHash returns hash of the embed content without timestamp
+func embedHash(embed *discordgo.MessageEmbed) (uint64, error) {
+	e := *embed
+	e.Timestamp = ""
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal embed: %w", err)
+	}
+
+	return xxh3.Hash(data), nil
+}
+
+// parseColor parses color in hex format like #2ECC71, 0x2ECC71 or 2ECC71
+func parseColor(s string) (int, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(s), "#"), "0x")
+
+	value, err := strconv.ParseUint(s, 16, 24)
+	if err != nil {
+		return 0, fmt.Errorf("invalid color %q: %w", s, err)
+	}
+
+	return int(value), nil
+}
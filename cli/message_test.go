@@ -0,0 +1,129 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/woozymasta/a2s/pkg/a2s"
+)
+
+func TestStatusMessageRender(t *testing.T) {
+	cfg := &Config{StatusMessage: &StatusMessage{
+		ChannelID:   "1",
+		Title:       "{{ .ID }} " + strings.Repeat("ж", maxEmbedTitle),
+		Description: "  {{ .Info.Map }}  ",
+		Color:       "{{ if .Info }}#2ECC71{{ else }}#E74C3C{{ end }}",
+		Footer:      "{{ .Host }}",
+		Fields: []EmbedField{
+			{Name: "Players", Value: "{{ .Info.Players }}/{{ .Info.MaxPlayers }}", Inline: true},
+			{Name: "Empty", Value: "{{ if false }}x{{ end }}"},
+		},
+	}}
+	if err := cfg.compileTemplates(); err != nil {
+		t.Fatal(err)
+	}
+
+	embed := cfg.StatusMessage.render(&TemplateData{ID: "s", Host: "h", Info: &a2s.Info{Map: "chernarusplus", Players: 3, MaxPlayers: 60}})
+
+	if len(embed.Title) > maxEmbedTitle || !utf8.ValidString(embed.Title) || !strings.HasSuffix(embed.Title, "...") {
+		t.Errorf("title %q is not cut to %d bytes at a rune boundary", embed.Title, maxEmbedTitle)
+	}
+	if embed.Description != "chernarusplus" {
+		t.Errorf("description = %q", embed.Description)
+	}
+	if embed.Color != colorOnline {
+		t.Errorf("color = %#x", embed.Color)
+	}
+	if embed.Footer == nil || embed.Footer.Text != "h" {
+		t.Errorf("footer = %+v", embed.Footer)
+	}
+	if len(embed.Fields) != 1 || embed.Fields[0].Value != "3/60" || !embed.Fields[0].Inline {
+		t.Errorf("fields = %+v, expected only the non-empty field", embed.Fields)
+	}
+
+	// Without templates the fallback embed is used
+	if embed := (&StatusMessage{ChannelID: "1"}).render(&TemplateData{}); embed != nil {
+		t.Errorf("embed = %+v, expected nil without templates", embed)
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+		err  bool
+	}{
+		{"#2ECC71", 0x2ECC71, false},
+		{"0x2ecc71", 0x2ECC71, false},
+		{"ff0000", 0xFF0000, false},
+		{"#1000000", 0, true},
+		{"red", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseColor(tt.in)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("parseColor(%q) = %#x, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestEmbedHash(t *testing.T) {
+	a := &discordgo.MessageEmbed{Title: "a", Timestamp: "2025-01-01T00:00:00Z"}
+	b := &discordgo.MessageEmbed{Title: "a", Timestamp: "2025-01-02T00:00:00Z"}
+	c := &discordgo.MessageEmbed{Title: "b"}
+
+	ha, _ := embedHash(a)
+	hb, _ := embedHash(b)
+	hc, _ := embedHash(c)
+
+	if ha != hb {
+		t.Error("timestamp changes the hash")
+	}
+	if ha == hc {
+		t.Error("different embeds have the same hash")
+	}
+	if a.Timestamp == "" {
+		t.Error("embed is modified")
+	}
+}
+
+func TestServerIDs(t *testing.T) {
+	if ids := serverIDs(&TemplateData{ID: "a"}); !slices.Equal(ids, []string{"a"}) {
+		t.Errorf("server IDs = %q", ids)
+	}
+	if ids := serverIDs(&SummaryData{Servers: []*TemplateData{{ID: "a"}, {ID: "b"}}}); !slices.Equal(ids, []string{"a", "b"}) {
+		t.Errorf("summary IDs = %q", ids)
+	}
+	if ids := serverIDs(nil); ids != nil {
+		t.Errorf("IDs of unknown data = %q", ids)
+	}
+}
+
+func TestStatusMessageGraph(t *testing.T) {
+	memory := &HistoryConfig{Windows: []string{"24h"}}
+	tests := []struct {
+		graph string
+		h     *HistoryConfig
+		err   bool
+	}{
+		{"", memory, false},
+		{"24h", memory, false},
+		{"7d", memory, true}, // Longer than the in-memory history
+		{"7d", &HistoryConfig{Windows: []string{"24h"}, DataDir: "data"}, false},
+		{"1h", memory, true},
+	}
+
+	for _, tt := range tests {
+		if err := (&StatusMessage{Graph: tt.graph}).validate(tt.h); (err != nil) != tt.err {
+			t.Errorf("graph %q: error = %v", tt.graph, err)
+		}
+	}
+
+	if (&StatusMessage{}).graphInterval() != defaultGraphInterval {
+		t.Error("graph interval is not the default one")
+	}
+}
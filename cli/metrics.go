@@ -0,0 +1,117 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Namespace of all bot metrics
+const metricsNamespace = "discord_a2s_bot"
+
+// Results of Discord API calls used as label values
+const (
+	resultSuccess     = "success"
+	resultError       = "error"
+	resultRateLimited = "rate_limited"
+)
+
+var (
+	serverLabels = []string{"server"}
+
+	metricServerUp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "server_up",
+		Help:      "Whether the last A2S query of the server was successful (1) or not (0)",
+	}, serverLabels)
+
+	metricServerPlayers = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "server_players",
+		Help:      "Number of players on the server",
+	}, serverLabels)
+
+	metricServerMaxPlayers = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "server_max_players",
+		Help:      "Maximum number of players the server can hold",
+	}, serverLabels)
+
+	metricServerQueue = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "server_queue",
+		Help:      "Number of players in the queue (DayZ only)",
+	}, serverLabels)
+
+	metricServerLatency = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "server_query_latency_seconds",
+		Help:      "Latency of the last successful A2S_INFO query",
+	}, serverLabels)
+
+	metricServerUptime = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "server_uptime_ratio",
+		Help:      "Ratio of successful A2S_INFO queries of the server within the window (24h, 7d, 30d)",
+	}, []string{"server", "window"})
+
+	metricChannelEdits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "discord_channel_edits_total",
+		Help:      "Number of Discord channel/category edits by result",
+	}, []string{"result"})
+
+	metricPresenceUpdates = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "discord_presence_updates_total",
+		Help:      "Number of Discord Rich Presence updates by result",
+	}, []string{"result"})
+
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "channel_update_queue_length",
+		Help:      "Number of channel update tasks waiting in the queue",
+	}, func() float64 {
+		return float64(len(channelUpdateQueue))
+	})
+
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "discord_channel_edits_pending",
+		Help:      "Number of channel edits waiting for the per-channel rate limit budget",
+	}, func() float64 {
+		return float64(channelScheduler.pending())
+	})
+)
+
+// observeServer sets the server gauges from the query result
+func observeServer(tpl *TemplateData, queue int) {
+	if tpl.Info == nil {
+		metricServerUp.WithLabelValues(tpl.ID).Set(0)
+		metricServerPlayers.WithLabelValues(tpl.ID).Set(0)
+		metricServerQueue.WithLabelValues(tpl.ID).Set(0)
+		return
+	}
+
+	metricServerUp.WithLabelValues(tpl.ID).Set(1)
+	metricServerPlayers.WithLabelValues(tpl.ID).Set(float64(tpl.Info.Players))
+	metricServerMaxPlayers.WithLabelValues(tpl.ID).Set(float64(tpl.Info.MaxPlayers))
+	metricServerQueue.WithLabelValues(tpl.ID).Set(float64(queue))
+	metricServerLatency.WithLabelValues(tpl.ID).Set(tpl.Info.Ping.Seconds())
+}
+
+// forgetServer removes the gauges of the server removed from configuration
+func forgetServer(id string) {
+	metricServerUp.DeleteLabelValues(id)
+	metricServerPlayers.DeleteLabelValues(id)
+	metricServerMaxPlayers.DeleteLabelValues(id)
+	metricServerQueue.DeleteLabelValues(id)
+	metricServerLatency.DeleteLabelValues(id)
+	metricServerUptime.DeletePartialMatch(prometheus.Labels{"server": id})
+}
+
+// observeUptime sets the uptime gauges of the server
+func observeUptime(id string, up *Uptime) {
+	for i, value := range up.windows() {
+		metricServerUptime.WithLabelValues(id, uptimeWindows[i]).Set(value / 100)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/woozymasta/a2s/pkg/a2s"
+)
+
+func TestObserveServer(t *testing.T) {
+	const id = "metrics"
+	t.Cleanup(func() { forgetServer(id) })
+
+	observeServer(&TemplateData{ID: id, Info: &a2s.Info{Players: 7, MaxPlayers: 60, Ping: 25 * time.Millisecond}}, 3)
+
+	for name, tt := range map[string]struct {
+		got, want float64
+	}{
+		"up":          {testutil.ToFloat64(metricServerUp.WithLabelValues(id)), 1},
+		"players":     {testutil.ToFloat64(metricServerPlayers.WithLabelValues(id)), 7},
+		"max players": {testutil.ToFloat64(metricServerMaxPlayers.WithLabelValues(id)), 60},
+		"queue":       {testutil.ToFloat64(metricServerQueue.WithLabelValues(id)), 3},
+		"latency":     {testutil.ToFloat64(metricServerLatency.WithLabelValues(id)), 0.025},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, expected %v", name, tt.got, tt.want)
+		}
+	}
+
+	// Offline server resets players and queue, but keeps the last known max players
+	observeServer(&TemplateData{ID: id}, 0)
+	if up := testutil.ToFloat64(metricServerUp.WithLabelValues(id)); up != 0 {
+		t.Errorf("up = %v, expected 0", up)
+	}
+	if players := testutil.ToFloat64(metricServerPlayers.WithLabelValues(id)); players != 0 {
+		t.Errorf("players = %v, expected 0", players)
+	}
+	if maxPlayers := testutil.ToFloat64(metricServerMaxPlayers.WithLabelValues(id)); maxPlayers != 60 {
+		t.Errorf("max players = %v, expected 60", maxPlayers)
+	}
+
+	forgetServer(id)
+	if n := testutil.CollectAndCount(metricServerUp); n != 0 {
+		t.Errorf("server_up series = %d after forgetting the server", n)
+	}
+}
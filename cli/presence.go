@@ -2,11 +2,68 @@ package main
 
 import (
 	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/rs/zerolog/log"
+	"github.com/woozymasta/a2s/pkg/keywords"
+	"github.com/zeebo/xxh3"
 )
 
+const (
+	maxPresenceText = 128 // Maximum length of Discord activity text
+
+	defaultPresenceOnline  = "{{ .Stats.Players }}/{{ .Stats.Slots }}{{ if .Stats.Queue }} (+{{ .Stats.Queue }}){{ end }} players{{ if gt .Stats.Servers 1 }} on {{ if lt .Stats.OnlineServers .Stats.Servers }}{{ .Stats.OnlineServers }}/{{ end }}{{ .Stats.Servers }} servers{{ end }}"
+	defaultPresenceOffline = "{{ if gt .Stats.Servers 1 }}All servers offline{{ else }}Server offline{{ end }}"
+)
+
+// presenceActivities maps the configured activity names to Discord activity types
+var presenceActivities = map[string]discordgo.ActivityType{
+	"playing":   discordgo.ActivityTypeGame,
+	"watching":  discordgo.ActivityTypeWatching,
+	"competing": discordgo.ActivityTypeCompeting,
+	"custom":    discordgo.ActivityTypeCustom,
+}
+
+// presenceStatuses lists the statuses allowed for the bot
+var presenceStatuses = map[string]struct{}{
+	string(discordgo.StatusOnline):       {},
+	string(discordgo.StatusIdle):         {},
+	string(discordgo.StatusDoNotDisturb): {},
+}
+
+/*
+Presence represents the configuration of the bot Rich Presence.
+
+Templates are rendered with SummaryData, the online template is used while
+at least one server is online, the offline template when all servers are offline.
+*/
+type Presence struct {
+	Online        string `yaml:"online,omitempty"`                        // Template for the presence while any server is online
+	Offline       string `yaml:"offline,omitempty"`                       // Template for the presence while all servers are offline
+	Status        string `yaml:"status,omitempty" default:"online"`       // Bot status while any server is online (online, idle, dnd)
+	OfflineStatus string `yaml:"offline_status,omitempty" default:"idle"` // Bot status while all servers are offline (online, idle, dnd)
+	Activity      string `yaml:"activity,omitempty" default:"custom"`     // Activity type (playing, watching, competing, custom)
+
+	Rotate         []PresenceRotation `yaml:"rotate,omitempty"`                        // Templates to cycle through instead of online/offline
+	RotateInterval time.Duration      `yaml:"rotate_interval,omitempty" default:"15s"` // Interval of switching to the next rotation template
+
+	OnlineFile  string `yaml:"online_file,omitempty"`  // File with template for the online presence
+	OfflineFile string `yaml:"offline_file,omitempty"` // File with template for the offline presence
+
+	tplOnline  *template.Template // Compiled Online or the default one
+	tplOffline *template.Template // Compiled Offline or the default one
+}
+
+// PresenceSender sends Rich Presence updates and remembers the last sent presence
+type PresenceSender struct {
+	prevHash uint64 // Hash of the previous Rich Presence
+	mu       sync.Mutex
+}
+
 /*
 PresenceStats holds the statistics used to update Discord Rich Presence.
 
@@ -21,32 +78,40 @@ type PresenceStats struct {
 	Queue         int // Number of players in the queue
 }
 
-/*
-update updates the Discord Rich Presence based on the current statistics.
+// statsOf aggregates the statistics of the servers
+func statsOf(servers []*TemplateData) *PresenceStats {
+	stats := &PresenceStats{Servers: len(servers)}
 
-It checks if the cumulative online status has changed to avoid unnecessary updates.
-If changes are detected, it updates the Rich Presence and logs the action.
+	for _, tpl := range servers {
+		if tpl.Info == nil {
+			continue
+		}
 
-Parameters:
-  - ds: Discord session.
-  - cfg: Configuration settings.
+		stats.OnlineServers++
+		stats.Players += int(tpl.Info.Players)
+		stats.Slots += int(tpl.Info.MaxPlayers)
+		if dayz, ok := tpl.Extra.(*keywords.DayZ); ok && dayz != nil {
+			stats.Queue += int(dayz.PlayersQueue)
+		}
+	}
 
-Returns an error if the update operation fails.
-*/
-func (p *PresenceStats) update(ds *discordgo.Session, cfg *Config) error {
-	cumulative := p.OnlineServers + p.Players + p.Queue
+	return stats
+}
 
-	if cumulative == cfg.prevCumulativeOnline {
-		log.Debug().Msg("Skipping Rich Presence update; no changes detected")
-		return nil
+// validate checks the presence status and activity type
+func (p *Presence) validate() error {
+	if _, ok := presenceStatuses[p.Status]; !ok {
+		return fmt.Errorf("invalid presence status %q, expected online, idle or dnd", p.Status)
 	}
-
-	if err := ds.UpdateStatusComplex(p.makeUSD()); err != nil {
-		return fmt.Errorf("failed to set status: %w", err)
+	if _, ok := presenceStatuses[p.OfflineStatus]; !ok {
+		return fmt.Errorf("invalid presence offline_status %q, expected online, idle or dnd", p.OfflineStatus)
+	}
+	if _, ok := presenceActivities[p.Activity]; !ok {
+		return fmt.Errorf("invalid presence activity %q, expected playing, watching, competing or custom", p.Activity)
+	}
+	if p.RotateInterval < minRotateInterval {
+		return fmt.Errorf("presence rotate_interval %s is too short, minimum is %s", p.RotateInterval, minRotateInterval)
 	}
-
-	log.Debug().Msg("Rich Presence updated successfully")
-	cfg.prevCumulativeOnline = cumulative
 
 	return nil
 }
@@ -54,50 +119,92 @@ func (p *PresenceStats) update(ds *discordgo.Session, cfg *Config) error {
 /*
 makeUSD creates the UpdateStatusData for Discord Rich Presence.
 
-It constructs the presence message based on the current statistics,
-ensuring it does not exceed Discord's character limit.
+It renders the online or offline template depending on the number of online servers.
 */
-func (p *PresenceStats) makeUSD() discordgo.UpdateStatusData {
-	var presence, status string
+func (p *Presence) makeUSD(data *SummaryData) discordgo.UpdateStatusData {
+	tmpl, name := p.tplOnline, "online"
+	if data.Stats.OnlineServers == 0 {
+		tmpl, name = p.tplOffline, "offline"
+	}
 
-	if p.OnlineServers == 0 {
-		status = "idle"
+	return p.statusData(data.Stats, p.renderText(tmpl, data, name))
+}
 
-		if p.Servers == 1 {
-			presence = "Server offline"
-		} else {
-			presence = "All servers offline"
-		}
-	} else {
-		status = "online"
+// renderText renders the presence template, trims spaces and cuts the result to Discord's character limit
+func (p *Presence) renderText(tmpl *template.Template, data any, name string) string {
+	text, err := executeTemplate(tmpl, data)
+	if err != nil {
+		log.Error().Err(err).Msgf("Error rendering %s presence template", name)
+	}
 
-		if p.Queue == 0 {
-			presence = fmt.Sprintf("%d/%d players", p.Players, p.Slots)
-		} else {
-			presence = fmt.Sprintf("%d/%d (+%d) players", p.Players, p.Slots, p.Queue)
-		}
+	return truncate(strings.TrimSpace(text), maxPresenceText)
+}
 
-		if p.Servers > 1 && p.OnlineServers > 0 {
-			if p.OnlineServers < p.Servers {
-				presence += fmt.Sprintf(" on %d/%d servers", p.OnlineServers, p.Servers)
-			} else {
-				presence += fmt.Sprintf(" on %d servers", p.OnlineServers)
-			}
-		}
+/*
+statusData creates the UpdateStatusData with the presence text and the configured activity type.
+
+The bot status depends on the number of online servers.
+If the text is empty, the activity is cleared.
+*/
+func (p *Presence) statusData(stats *PresenceStats, text string) discordgo.UpdateStatusData {
+	status := p.Status
+	if stats.OnlineServers == 0 {
+		status = p.OfflineStatus
+	}
+
+	// Empty text clears the activity, only the status is set
+	if text == "" {
+		return discordgo.UpdateStatusData{Status: status, Activities: []*discordgo.Activity{}}
 	}
 
-	if len(presence) > 128 {
-		presence = presence[:125] + "..."
+	activity := &discordgo.Activity{
+		Name: text,
+		Type: presenceActivities[p.Activity],
+	}
+	if activity.Type == discordgo.ActivityTypeCustom {
+		activity.State = text
 	}
 
 	return discordgo.UpdateStatusData{
-		Status: status,
-		Activities: []*discordgo.Activity{
-			{
-				Name:  presence,
-				Type:  discordgo.ActivityTypeCustom,
-				State: presence,
-			},
-		},
+		Status:     status,
+		Activities: []*discordgo.Activity{activity},
+	}
+}
+
+/*
+set sends the presence to Discord if it differs from the previous one.
+
+It compares the hash of the presence with the previous one to avoid unnecessary updates.
+*/
+func (s *PresenceSender) set(ds *discordgo.Session, usd discordgo.UpdateStatusData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hash := presenceHash(usd)
+	if hash == s.prevHash {
+		log.Debug().Msg("Skipping Rich Presence update; no changes detected")
+		return nil
+	}
+
+	if err := ds.UpdateStatusComplex(usd); err != nil {
+		metricPresenceUpdates.WithLabelValues(resultError).Inc()
+		return fmt.Errorf("failed to set status: %w", err)
 	}
+	metricPresenceUpdates.WithLabelValues(resultSuccess).Inc()
+
+	log.Debug().Msg("Rich Presence updated successfully")
+	s.prevHash = hash
+
+	return nil
+}
+
+// presenceHash returns hash of the status, activity type and text of the presence
+func presenceHash(usd discordgo.UpdateStatusData) uint64 {
+	var sb strings.Builder
+	sb.WriteString(usd.Status)
+	for _, a := range usd.Activities {
+		fmt.Fprintf(&sb, "\x00%d\x00%s", a.Type, a.Name)
+	}
+
+	return xxh3.HashString(sb.String())
 }
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/woozymasta/a2s/pkg/a2s"
+	"github.com/woozymasta/a2s/pkg/keywords"
+)
+
+// testPresence returns the compiled presence with the default statuses and activity
+func testPresence(t *testing.T, p Presence) *Presence {
+	t.Helper()
+
+	cfg := &Config{}
+	cfg.Bot.Presence = p
+	cfg.Bot.Presence.Status, cfg.Bot.Presence.OfflineStatus = "online", "idle"
+	if cfg.Bot.Presence.Activity == "" {
+		cfg.Bot.Presence.Activity = "custom"
+	}
+	if err := cfg.compileTemplates(); err != nil {
+		t.Fatal(err)
+	}
+
+	return &cfg.Bot.Presence
+}
+
+func TestStatsOf(t *testing.T) {
+	stats := statsOf([]*TemplateData{
+		{ID: "a", Info: &a2s.Info{Players: 10, MaxPlayers: 60}, Extra: &keywords.DayZ{PlayersQueue: 4}},
+		{ID: "b", Info: &a2s.Info{Players: 2, MaxPlayers: 40}},
+		{ID: "c"},
+	})
+
+	want := PresenceStats{Servers: 3, OnlineServers: 2, Players: 12, Slots: 100, Queue: 4}
+	if *stats != want {
+		t.Errorf("stats = %+v, expected %+v", *stats, want)
+	}
+}
+
+func TestMakeUSD(t *testing.T) {
+	p := testPresence(t, Presence{})
+
+	tests := []struct {
+		name   string
+		stats  PresenceStats
+		text   string
+		status string
+	}{
+		{"one server", PresenceStats{Servers: 1, OnlineServers: 1, Players: 3, Slots: 60}, "3/60 players", "online"},
+		{"queue", PresenceStats{Servers: 1, OnlineServers: 1, Players: 60, Slots: 60, Queue: 5}, "60/60 (+5) players", "online"},
+		{"partly online", PresenceStats{Servers: 3, OnlineServers: 2, Players: 3, Slots: 60}, "3/60 players on 2/3 servers", "online"},
+		{"all online", PresenceStats{Servers: 2, OnlineServers: 2, Players: 3, Slots: 60}, "3/60 players on 2 servers", "online"},
+		{"offline", PresenceStats{Servers: 1}, "Server offline", "idle"},
+		{"all offline", PresenceStats{Servers: 2}, "All servers offline", "idle"},
+	}
+
+	for _, tt := range tests {
+		usd := p.makeUSD(&SummaryData{Stats: &tt.stats})
+		if usd.Status != tt.status || len(usd.Activities) != 1 {
+			t.Fatalf("%s: presence = %+v", tt.name, usd)
+		}
+		if a := usd.Activities[0]; a.Name != tt.text || a.State != tt.text || a.Type != discordgo.ActivityTypeCustom {
+			t.Errorf("%s: activity = %q %q %d, expected custom %q", tt.name, a.Name, a.State, a.Type, tt.text)
+		}
+	}
+}
+
+func TestMakeUSDTemplates(t *testing.T) {
+	p := testPresence(t, Presence{Online: "  {{ .Stats.Players }} on {{ (index .Servers 0).ID }} ", Offline: "{{ if false }}x{{ end }}", Activity: "watching"})
+
+	usd := p.makeUSD(&SummaryData{Stats: &PresenceStats{Servers: 1, OnlineServers: 1, Players: 7}, Servers: []*TemplateData{{ID: "srv"}}})
+	if a := usd.Activities[0]; a.Name != "7 on srv" || a.State != "" || a.Type != discordgo.ActivityTypeWatching {
+		t.Errorf("activity = %+v", a)
+	}
+
+	// Empty text clears the activity
+	usd = p.makeUSD(&SummaryData{Stats: &PresenceStats{Servers: 1}})
+	if usd.Status != "idle" || usd.Activities == nil || len(usd.Activities) != 0 {
+		t.Errorf("presence = %+v, expected idle status without activity", usd)
+	}
+}
+
+func TestPresenceValidate(t *testing.T) {
+	valid := Presence{Status: "online", OfflineStatus: "idle", Activity: "playing", RotateInterval: minRotateInterval}
+	if err := valid.validate(); err != nil {
+		t.Errorf("valid presence: %v", err)
+	}
+
+	for name, mutate := range map[string]func(p *Presence){
+		"status":          func(p *Presence) { p.Status = "invisible" },
+		"offline status":  func(p *Presence) { p.OfflineStatus = "offline" },
+		"activity":        func(p *Presence) { p.Activity = "listening" },
+		"rotate interval": func(p *Presence) { p.RotateInterval = minRotateInterval - 1 },
+	} {
+		p := valid
+		mutate(&p)
+		if err := p.validate(); err == nil {
+			t.Errorf("expected error for invalid %s", name)
+		}
+	}
+}
+
+func TestPresenceHash(t *testing.T) {
+	p := testPresence(t, Presence{})
+	stats := &PresenceStats{Servers: 1, OnlineServers: 1}
+
+	a := presenceHash(p.statusData(stats, "text"))
+	if a != presenceHash(p.statusData(stats, "text")) {
+		t.Error("same presence has different hashes")
+	}
+	if a == presenceHash(p.statusData(stats, "other")) {
+		t.Error("different text has the same hash")
+	}
+	if a == presenceHash(p.statusData(&PresenceStats{Servers: 1}, "text")) {
+		t.Error("different status has the same hash")
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// minQueryBuffer is the smallest buffer holding the packet header and the response type
+const minQueryBuffer = 5
+
+// QueryResult is the result of the --query command, it can be used as --render fixture
+type QueryResult struct {
+	Fixture
+	Extra   any    `json:"extra,omitempty"` // Parsed Arma 3/DayZ keywords
+	Latency string `json:"latency"`         // Latency of A2S_INFO
+}
+
+/*
+runQuery queries the server by address and prints the result, then exits.
+
+Arguments are the address in host:port format (port 27016 if omitted) and flags:
+--players, --rules, --json, --timeout <seconds> and --buffer <bytes>.
+*/
+func runQuery(args []string) {
+	srv, asJSON, err := parseQueryArgs(args)
+	if err != nil {
+		queryFatal(err)
+	}
+
+	tpl, _, err := srv.query()
+	if err != nil {
+		queryFatal(fmt.Errorf("failed to query %s: %w", srv.ID, err))
+	}
+
+	result := &QueryResult{
+		Fixture: Fixture{
+			Info:    tpl.Info,
+			Players: tpl.Players,
+			Rules:   tpl.Rules,
+			Mods:    tpl.Mods,
+		},
+		Extra:   tpl.Extra,
+		Latency: tpl.Info.Ping.String(),
+	}
+
+	if asJSON {
+		err = result.printJSON(os.Stdout)
+	} else {
+		err = result.printTable(os.Stdout)
+	}
+	if err != nil {
+		queryFatal(err)
+	}
+	os.Exit(0)
+}
+
+// parseQueryArgs parses the arguments of the query command to the server configuration and the JSON output flag
+func parseQueryArgs(args []string) (*ServerConfig, bool, error) {
+	srv := &ServerConfig{Timeout: 3, BufferSize: 1024}
+	var address string
+	var asJSON bool
+
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; arg {
+		case "--players", "-p":
+			srv.QueryPlayers = true
+		case "--rules", "-r":
+			srv.QueryRules = true
+		case "--json", "-j":
+			asJSON = true
+		case "--timeout", "-t", "--buffer", "-b":
+			if i+1 >= len(args) {
+				return nil, false, fmt.Errorf("missing value of %s", arg)
+			}
+			i++
+			value, err := strconv.ParseUint(args[i], 10, 16)
+			if err != nil {
+				return nil, false, fmt.Errorf("invalid value of %s: %w", arg, err)
+			}
+
+			if arg == "--timeout" || arg == "-t" {
+				if value < 1 {
+					return nil, false, fmt.Errorf("%s must be at least 1 second", arg)
+				}
+				srv.Timeout = int(value)
+			} else {
+				if value < minQueryBuffer {
+					return nil, false, fmt.Errorf("%s must be at least %d bytes to hold the packet header", arg, minQueryBuffer)
+				}
+				srv.BufferSize = uint16(value)
+			}
+		default:
+			address = arg
+		}
+	}
+
+	if err := srv.setAddress(address); err != nil {
+		return nil, false, err
+	}
+
+	return srv, asJSON, nil
+}
+
+// queryFatal prints the error and exits with code 1
+func queryFatal(err error) {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	os.Exit(1)
+}
+
+// setAddress sets the host and port of the server from the address, port 27016 is used if omitted
+func (s *ServerConfig) setAddress(address string) error {
+	if address == "" {
+		return fmt.Errorf("server address is required")
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		host, port = address, "27016"
+	}
+
+	s.Port, err = strconv.Atoi(port)
+	if err != nil {
+		return fmt.Errorf("invalid port %q: %w", port, err)
+	}
+	s.Host = host
+	s.ID = net.JoinHostPort(host, port)
+
+	return nil
+}
+
+// printJSON prints the result as indented JSON
+func (r *QueryResult) printJSON(out io.Writer) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(r)
+}
+
+// printTable prints the result as tables of info, keywords, players, rules and mods
+func (r *QueryResult) printTable(out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(w, "A2S_INFO\t(%s, %s)\n", r.Latency, tplHelperAppIDtoString(r.Info.ID))
+	printFields(w, r.Info)
+
+	if r.Extra != nil {
+		fmt.Fprintf(w, "\nKeywords\t\n")
+		printFields(w, r.Extra)
+	}
+
+	if r.Players != nil {
+		fmt.Fprintf(w, "\nA2S_PLAYER\t(%d)\n", len(r.Players))
+		fmt.Fprintf(w, "  Name\tScore\tDuration\n")
+		for _, p := range r.Players {
+			fmt.Fprintf(w, "  %s\t%d\t%s\n", orDash(p.Name), p.Score, p.Duration.Truncate(time.Second))
+		}
+	}
+
+	if r.Rules != nil {
+		fmt.Fprintf(w, "\nA2S_RULES\t(%d)\n", len(r.Rules))
+		for _, k := range slices.Sorted(maps.Keys(r.Rules)) {
+			fmt.Fprintf(w, "  %s\t%s\n", k, r.Rules[k])
+		}
+	}
+
+	if r.Mods != nil {
+		fmt.Fprintf(w, "\nMods\t(%d)\n", len(r.Mods))
+		for _, m := range r.Mods {
+			fmt.Fprintf(w, "  %s\t%d\n", m.Name, m.ID)
+		}
+	}
+
+	return w.Flush()
+}
+
+/*
+printFields prints the exported fields of the struct as key/value lines.
+
+Keys are taken from JSON tags, empty values are skipped, durations and lists are
+formatted to be readable.
+*/
+func printFields(w io.Writer, v any) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		fmt.Fprintf(w, "  %v\t\n", v)
+		return
+	}
+
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Type().Field(i)
+		value := rv.Field(i)
+		if !field.IsExported() || value.IsZero() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			name = field.Name
+		}
+
+		switch {
+		case value.Type() == durationType:
+			fmt.Fprintf(w, "  %s\t%s\n", name, time.Duration(value.Int()))
+		case value.Kind() == reflect.Slice:
+			items := make([]string, value.Len())
+			for j := range items {
+				items[j] = fmt.Sprint(value.Index(j).Interface())
+			}
+			fmt.Fprintf(w, "  %s\t%s\n", name, strings.Join(items, ", "))
+		default:
+			fmt.Fprintf(w, "  %s\t%v\n", name, reflect.Indirect(value).Interface())
+		}
+	}
+}
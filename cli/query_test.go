@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/woozymasta/a2s/pkg/a2s"
+)
+
+func TestParseQueryArgs(t *testing.T) {
+	srv, asJSON, err := parseQueryArgs([]string{"example.com", "-p", "--rules", "-j", "--timeout", "5", "-b", "4096"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srv.Host != "example.com" || srv.Port != 27016 || !srv.QueryPlayers || !srv.QueryRules || !asJSON || srv.Timeout != 5 || srv.BufferSize != 4096 {
+		t.Errorf("server = %+v, json = %t", srv, asJSON)
+	}
+
+	if srv, _, err = parseQueryArgs([]string{"127.0.0.1:2303"}); err != nil || srv.Port != 2303 || srv.Timeout != 3 || srv.BufferSize != 1024 {
+		t.Errorf("server = %+v, %v, expected defaults", srv, err)
+	}
+
+	for _, args := range [][]string{
+		{},
+		{"127.0.0.1:port"},
+		{"127.0.0.1", "--timeout"},
+		{"127.0.0.1", "--timeout", "0"},
+		{"127.0.0.1", "--timeout", "-1"},
+		{"127.0.0.1", "--buffer", "4"},
+		{"127.0.0.1", "-b", "65536"},
+	} {
+		if _, _, err := parseQueryArgs(args); err == nil {
+			t.Errorf("parseQueryArgs(%q): expected error", args)
+		}
+	}
+}
+
+func TestQueryMinBuffer(t *testing.T) {
+	f := &fakeServer{info: &a2s.Info{Name: "Test", Map: "map", ID: 730}}
+	srv := f.start(t)
+	srv.BufferSize = minQueryBuffer
+
+	// Too small buffer fails the query instead of panicking
+	if _, _, err := srv.query(); err == nil {
+		t.Error("expected error for truncated response")
+	}
+}
+
+func TestQueryResultPrint(t *testing.T) {
+	f := &fakeServer{
+		info:    &a2s.Info{Name: "Test", Map: "chernarusplus", Players: 1, MaxPlayers: 60, ID: 730},
+		players: []a2s.Player{{Name: "alpha", Score: 3, Duration: 90 * time.Second}},
+		rules:   map[string]string{"b": "2", "a": "1"},
+	}
+	srv := f.start(t)
+	srv.QueryPlayers, srv.QueryRules = true, true
+
+	tpl, _, err := srv.query()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := &QueryResult{Fixture: Fixture{Info: tpl.Info, Players: tpl.Players, Rules: tpl.Rules}, Extra: tpl.Extra, Latency: "1ms"}
+
+	var out bytes.Buffer
+	if err := result.printTable(&out); err != nil {
+		t.Fatal(err)
+	}
+	table := out.String()
+	for _, want := range []string{"A2S_INFO", "chernarusplus", "A2S_PLAYER  (1)", "alpha", "1m30s", "A2S_RULES"} {
+		if !strings.Contains(table, want) {
+			t.Errorf("table does not contain %q:\n%s", want, table)
+		}
+	}
+	if strings.Index(table, "  a  ") > strings.Index(table, "  b  ") {
+		t.Errorf("rules are not sorted:\n%s", table)
+	}
+
+	// JSON output is a valid fixture for --render
+	out.Reset()
+	if err := result.printJSON(&out); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	if err := os.WriteFile(path, out.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fixture, _, err := srv.loadFixture(path)
+	if err != nil || fixture.Info.Map != "chernarusplus" || len(fixture.Players) != 1 || fixture.Rules["b"] != "2" {
+		t.Errorf("fixture = %+v, %v", fixture, err)
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// configWatchInterval is the interval of checking the configuration file for changes
+const configWatchInterval = 5 * time.Second
+
+// activeConfig is the current configuration for event handlers, it is replaced on reload
+var activeConfig atomic.Pointer[Config]
+
+/*
+watchConfig polls the configuration file and template files of the active configuration
+and sends to the returned channel when any of them changes.
+
+Polling is used instead of file system notifications, it works the same way on all
+platforms and with files replaced by editors or mounted from Kubernetes ConfigMaps.
+Template files added by a reload are only compared from the next poll.
+*/
+func watchConfig(path string) <-chan struct{} {
+	changed := make(chan struct{}, 1)
+	prev := watchedStamps(path)
+
+	go func() {
+		ticker := time.NewTicker(configWatchInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			stamps := watchedStamps(path)
+			file, ok := changedFile(prev, stamps)
+			prev = stamps
+			if !ok {
+				continue
+			}
+
+			log.Debug().Str("path", file).Msg("Configuration file changed")
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	return changed
+}
+
+// watchedStamps returns stamps of the configuration file and template files of the active configuration
+func watchedStamps(path string) map[string][2]int64 {
+	stamps := map[string][2]int64{path: fileStamp(path)}
+	if cfg := activeConfig.Load(); cfg != nil {
+		for _, file := range cfg.templateFiles {
+			stamps[file] = fileStamp(file)
+		}
+	}
+
+	return stamps
+}
+
+// changedFile returns the first file with a stamp different from the previous one
+func changedFile(prev, cur map[string][2]int64) (string, bool) {
+	for file, stamp := range cur {
+		if old, ok := prev[file]; ok && old != stamp {
+			return file, true
+		}
+	}
+
+	return "", false
+}
+
+// fileStamp returns the modification time and size of the file, zero values if it does not exist
+func fileStamp(path string) [2]int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return [2]int64{}
+	}
+
+	return [2]int64{info.ModTime().UnixNano(), info.Size()}
+}
+
+/*
+reloadConfig reads and validates the new configuration and carries over the runtime state.
+
+Servers are matched by ID, state of unchanged servers is kept, so their channels
+are not edited again. Options that require a restart are kept from the current
+configuration. On error the current configuration stays active.
+*/
+func reloadConfig(cur *Config) (*Config, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Bot.Token != cur.Bot.Token {
+		log.Warn().Msg("Changing bot token requires restart, keeping the current one")
+		cfg.Bot.Token = cur.Bot.Token
+	}
+	if cfg.Bot.Concurrency != cur.Bot.Concurrency {
+		log.Warn().Msg("Changing concurrency requires restart, keeping the current one")
+		cfg.Bot.Concurrency = cur.Bot.Concurrency
+	}
+	if cfg.Bot.NoCommands != cur.Bot.NoCommands {
+		log.Warn().Msg("Changing no_commands requires restart, keeping the current one")
+		cfg.Bot.NoCommands = cur.Bot.NoCommands
+	}
+	if cfg.Bot.StateFile != cur.Bot.StateFile {
+		log.Warn().Msg("Changing state_file requires restart, keeping the current one")
+		cfg.Bot.StateFile = cur.Bot.StateFile
+	}
+	if cfg.History.DataDir != cur.History.DataDir {
+		log.Warn().Msg("Changing history data_dir requires restart, keeping the current one")
+		cfg.History.DataDir = cur.History.DataDir
+	}
+	if !sameTokens(cfg.Bots, cur.Bots) {
+		log.Warn().Msg("Changing tokens or number of additional bots requires restart, keeping the current bots")
+		cfg.Bots = cur.Bots
+		if err := cfg.validateBots(cfg.serverIDSet()); err != nil {
+			return nil, fmt.Errorf("current bots do not match the new servers: %w", err)
+		}
+	}
+	if cfg.HTTP != cur.HTTP {
+		log.Warn().Msg("Changing http settings requires restart, keeping the current ones")
+		cfg.HTTP = cur.HTTP
+	}
+	if cfg.Logging != cur.Logging {
+		cfg.Logging.setup()
+	}
+
+	cfg.StatusMessage.carryState(cur.StatusMessage)
+
+	prev := make(map[string]*ServerConfig, len(cur.Servers))
+	for i := range cur.Servers {
+		prev[cur.Servers[i].ID] = &cur.Servers[i]
+	}
+
+	var added, kept int
+	for i := range cfg.Servers {
+		srv := &cfg.Servers[i]
+		old, ok := prev[srv.ID]
+		if !ok {
+			added++
+			continue
+		}
+
+		srv.carryState(old)
+		delete(prev, srv.ID)
+		kept++
+	}
+
+	channelScheduler.prune(cfg.channelIDs())
+
+	for id := range prev {
+		dataCache.delete(id)
+		history.delete(id)
+		uptimes.delete(id)
+		botState.deleteServer(id)
+		forgetServer(id)
+	}
+
+	log.Info().
+		Int("added", added).
+		Int("kept", kept).
+		Int("removed", len(prev)).
+		Msg("Configuration reloaded")
+
+	return cfg, nil
+}
+
+/*
+carryState copies the runtime state of the same server from the previous configuration.
+
+Hashes of applied channel edits are kept by the channelScheduler by channel ID,
+so they survive the reload without copying.
+*/
+func (s *ServerConfig) carryState(old *ServerConfig) {
+	s.failedSince = old.failedSince
+	s.failures = old.failures
+	s.state = old.state
+	s.alerted = old.alerted
+	s.players = old.players
+	s.playersKnown = old.playersKnown
+
+	s.StatusMessage.carryState(old.StatusMessage)
+}
+
+// carryState copies the posted message ID and graph state from the previous configuration if the channel is the same
+func (m *StatusMessage) carryState(old *StatusMessage) {
+	if m == nil || old == nil || m.ChannelID != old.ChannelID {
+		return
+	}
+
+	old.mu.Lock()
+	defer old.mu.Unlock()
+
+	if m.MessageID != "" && m.MessageID != old.MessageID {
+		return
+	}
+
+	m.MessageID = old.MessageID
+	m.prevHash = old.prevHash
+	m.graphAt = old.graphAt
+}
+
+// channelIDs returns the IDs of all configured channels and categories edited by the scheduler
+func (c *Config) channelIDs() map[string]struct{} {
+	ids := make(map[string]struct{}, 2*len(c.Servers))
+	for _, srv := range c.Servers {
+		if srv.ChannelID != "" {
+			ids[srv.ChannelID] = struct{}{}
+		}
+		if srv.CategoryID != "" {
+			ids[srv.CategoryID] = struct{}{}
+		}
+	}
+
+	return ids
+}
+
+// sameTokens checks that both lists have the same bot tokens in the same order
+func sameTokens(a, b []BotIdentity) bool {
+	return slices.EqualFunc(a, b, func(x, y BotIdentity) bool {
+		return x.Token == y.Token
+	})
+}
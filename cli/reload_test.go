@@ -0,0 +1,116 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// useConfig writes the configuration file and makes it the one loaded by loadConfig
+func useConfig(t *testing.T, content string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	prev := configFile
+	configFile = path
+	t.Cleanup(func() { configFile = prev })
+}
+
+func TestReloadConfig(t *testing.T) {
+	useConfig(t, `
+bot:
+  token: old
+  concurrency: 5
+servers:
+  - id: kept
+    port: 2302
+    status_message:
+      channel_id: "10"
+  - id: removed
+    port: 2402
+`)
+	cur, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	graphAt := time.Now().Add(-time.Minute)
+	kept := &cur.Servers[0]
+	kept.state, kept.failures, kept.alerted = stateOffline, 3, true
+	kept.StatusMessage.MessageID, kept.StatusMessage.prevHash, kept.StatusMessage.graphAt = "20", 42, graphAt
+	dataCache.set(&TemplateData{ID: "removed"})
+
+	useConfig(t, `
+bot:
+  token: new
+  concurrency: 20
+servers:
+  - id: kept
+    port: 2302
+    status_message:
+      channel_id: "10"
+  - id: added
+    port: 2502
+`)
+	cfg, err := reloadConfig(cur)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Bot.Token != "old" || cfg.Bot.Concurrency != 5 {
+		t.Errorf("token = %q, concurrency = %d, expected options requiring restart to be kept", cfg.Bot.Token, cfg.Bot.Concurrency)
+	}
+
+	srv := cfg.Servers[0]
+	if srv.state != stateOffline || srv.failures != 3 || !srv.alerted {
+		t.Errorf("state of kept server = %s, %d failures, alerted %t", srv.state, srv.failures, srv.alerted)
+	}
+	if m := srv.StatusMessage; m.MessageID != "20" || m.prevHash != 42 || !m.graphAt.Equal(graphAt) {
+		t.Errorf("status message = %q, %d, %s", m.MessageID, m.prevHash, m.graphAt)
+	}
+	if cfg.Servers[1].state != stateUnknown {
+		t.Errorf("state of added server = %s", cfg.Servers[1].state)
+	}
+	if _, ok := dataCache.get("removed"); ok {
+		t.Error("cached data of removed server is kept")
+	}
+
+	// Invalid configuration is rejected
+	useConfig(t, "bot:\n  token: old\nservers:\n  - id: a\n  - id: a\n")
+	if _, err := reloadConfig(cfg); err == nil {
+		t.Error("expected error for duplicate server IDs")
+	}
+}
+
+func TestStatusMessageCarryState(t *testing.T) {
+	old := &StatusMessage{ChannelID: "1", MessageID: "2", prevHash: 3, graphAt: time.Now()}
+
+	tests := []struct {
+		name string
+		msg  *StatusMessage
+		want string
+	}{
+		{"same channel", &StatusMessage{ChannelID: "1"}, "2"},
+		{"other channel", &StatusMessage{ChannelID: "9"}, ""},
+		{"other message set", &StatusMessage{ChannelID: "1", MessageID: "5"}, "5"},
+	}
+
+	for _, tt := range tests {
+		tt.msg.carryState(old)
+		if tt.msg.MessageID != tt.want {
+			t.Errorf("%s: message ID = %q, expected %q", tt.name, tt.msg.MessageID, tt.want)
+		}
+		if carried := tt.msg.prevHash == old.prevHash && tt.msg.graphAt.Equal(old.graphAt); carried != (tt.want == old.MessageID) {
+			t.Errorf("%s: hash and graph time carried = %t", tt.name, carried)
+		}
+	}
+
+	// Nil messages are ignored
+	(*StatusMessage)(nil).carryState(old)
+	(&StatusMessage{ChannelID: "1"}).carryState(nil)
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/woozymasta/a2s/pkg/a2s"
+	"github.com/woozymasta/a2s/pkg/a3sb"
+)
+
+/*
+Fixture is a saved query result of a server.
+
+It allows to render templates without querying the server, e.g. to iterate
+on templates for a server which is offline or not reachable from the workstation.
+Extra keywords are parsed from Info.Keywords the same way as for the live query.
+*/
+type Fixture struct {
+	Info    *a2s.Info         `json:"info"`              // A2S_INFO response, null for offline server
+	Players []a2s.Player      `json:"players,omitempty"` // A2S_PLAYER response
+	Rules   map[string]string `json:"rules,omitempty"`   // A2S_RULES response
+	Mods    []a3sb.Mod        `json:"mods,omitempty"`    // Mods decoded from Arma 3/DayZ rules
+}
+
+// renderItem is one configured template with its data and Discord length limit
+type renderItem struct {
+	name  string             // Path of the template in configuration
+	tmpl  *template.Template // Compiled template, nil if not configured
+	data  any                // Data to render the template with
+	limit int                // Discord length limit, 0 means no limit
+	cut   bool               // Longer results are cut by the bot, otherwise Discord rejects them
+}
+
+/*
+runRender renders every configured template of the server and exits.
+
+Arguments are the server ID, optional --fixture <file> to load the query result
+from JSON instead of querying the server, optional --save <file> to save the
+query result as fixture, and an optional path to the configuration file.
+*/
+func runRender(args []string) {
+	id, fixture, save, err := parseRenderArgs(args)
+	if err == nil {
+		err = render(os.Stdout, id, fixture, save)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// parseRenderArgs parses the arguments of the render command, the configuration path is set to configFile
+func parseRenderArgs(args []string) (id, fixture, save string, err error) {
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--fixture" || arg == "-f" || arg == "--save" || arg == "-s":
+			if i+1 >= len(args) {
+				return "", "", "", fmt.Errorf("%s requires a file path", arg)
+			}
+			i++
+			if arg == "--fixture" || arg == "-f" {
+				fixture = args[i]
+			} else {
+				save = args[i]
+			}
+		case id == "":
+			id = arg
+		default:
+			configFile = arg
+		}
+	}
+
+	return id, fixture, save, nil
+}
+
+// render loads the query result of the server and prints all its rendered templates
+func render(out io.Writer, id, fixture, save string) error {
+	if id == "" {
+		return fmt.Errorf("server id is required")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	srv := cfg.server(id)
+	if srv == nil {
+		return fmt.Errorf("server %q not found in configuration", id)
+	}
+
+	var tpl *TemplateData
+	var queue int
+	if fixture != "" {
+		tpl, queue, err = srv.loadFixture(fixture)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Server %s rendered from fixture %s\n", srv.ID, fixture)
+	} else {
+		tpl, queue, err = srv.query()
+		if err != nil {
+			fmt.Fprintf(out, "Server %s is offline: %v\n", srv.ID, err)
+		} else {
+			fmt.Fprintf(out, "Server %s queried in %s\n", srv.ID, tpl.Info.Ping)
+		}
+	}
+
+	if save != "" {
+		if err := tpl.saveFixture(save); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Query result saved to %s\n", save)
+	}
+
+	// History and uptime have only this one sample, so windows show the current values
+	sample := newSample(tpl, queue, time.Now())
+	tpl.History = history.record(srv.ID, sample, cfg)
+	tpl.Uptime = uptimes.record(srv.ID, sample)
+
+	for _, item := range srv.renderItems(cfg, tpl) {
+		item.print(out)
+	}
+
+	return nil
+}
+
+// server returns the server configuration by ID, nil if not found
+func (c *Config) server(id string) *ServerConfig {
+	for i := range c.Servers {
+		if c.Servers[i].ID == id {
+			return &c.Servers[i]
+		}
+	}
+
+	return nil
+}
+
+// renderItems collects all configured templates of the server with their limits
+func (s *ServerConfig) renderItems(cfg *Config, tpl *TemplateData) []renderItem {
+	items := []renderItem{
+		{"channel_name", s.tplChannelName, tpl, maxChannelName, false},
+		{"channel_description", s.tplChannelDesc, tpl, maxChannelTopic, true},
+		{"category_name", s.tplCategoryName, tpl, maxChannelName, false},
+	}
+
+	if m := s.StatusMessage; m != nil {
+		items = append(items,
+			renderItem{"status_message.title", m.tplTitle, tpl, maxEmbedTitle, true},
+			renderItem{"status_message.description", m.tplDescription, tpl, maxEmbedDescription, true},
+			renderItem{"status_message.color", m.tplColor, tpl, 0, false},
+			renderItem{"status_message.footer", m.tplFooter, tpl, maxEmbedFooter, true},
+		)
+		for i, f := range m.Fields {
+			items = append(items,
+				renderItem{fmt.Sprintf("status_message.fields.%d.name", i), f.tplName, tpl, maxEmbedFieldName, true},
+				renderItem{fmt.Sprintf("status_message.fields.%d.value", i), f.tplValue, tpl, maxEmbedFieldValue, true},
+			)
+		}
+	}
+
+	// Only the alert matching the current state of the server is rendered
+	name, tmpl := "alerts.online_message", cfg.Alerts.tplOnline
+	if tpl.Info == nil {
+		name, tmpl = "alerts.offline_message", cfg.Alerts.tplOffline
+	}
+	items = append(items, renderItem{name, tmpl, &AlertData{TemplateData: tpl}, maxMessageContent, true})
+
+	return items
+}
+
+/*
+print renders the template and prints the result with its length and warnings.
+
+Discord limits count characters, the bot cuts too long texts by bytes to stay
+within the limit for any text, so for cut templates the sent text is printed too.
+*/
+func (r renderItem) print(out io.Writer) {
+	if r.tmpl == nil {
+		return
+	}
+
+	rendered, err := executeTemplate(r.tmpl, r.data)
+	chars := utf8.RuneCountInString(rendered)
+
+	fmt.Fprintf(out, "\n%s", r.name)
+	if r.limit > 0 {
+		fmt.Fprintf(out, " (%d/%d characters", chars, r.limit)
+		if len(rendered) != chars {
+			fmt.Fprintf(out, ", %d bytes", len(rendered))
+		}
+		fmt.Fprint(out, ")")
+	}
+	fmt.Fprintf(out, ":\n  %s\n", indent(rendered))
+
+	switch {
+	case err != nil:
+		fmt.Fprintf(out, "error: %v\n", err)
+	case r.limit > 0 && r.cut && len(rendered) > r.limit:
+		fmt.Fprintf(out, "warning: longer than the limit of %d bytes, the bot sends it cut to:\n  %s\n", r.limit, indent(truncate(rendered, r.limit)))
+	case r.limit > 0 && !r.cut && chars > r.limit:
+		fmt.Fprintf(out, "warning: longer than the limit of %d characters, Discord may reject it\n", r.limit)
+	}
+}
+
+// indent indents the continuation lines of the text for printing under its name
+func indent(text string) string {
+	return strings.ReplaceAll(text, "\n", "\n  ")
+}
+
+// loadFixture reads the query result from the JSON file and builds the template data of the server with the number of players in the queue
+func (s *ServerConfig) loadFixture(path string) (*TemplateData, int, error) {
+	data, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read fixture: %w", err)
+	}
+
+	var f struct {
+		Fixture
+		Info *fixtureInfo `json:"info"`
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse fixture: %w", err)
+	}
+
+	tpl := &TemplateData{
+		ID:      s.ID,
+		Host:    s.Host,
+		Port:    s.Port,
+		Info:    f.Info.info(),
+		Players: f.Players,
+		Rules:   f.Rules,
+		Mods:    f.Mods,
+	}
+	var queue int
+	if tpl.Info != nil {
+		tpl.Extra, queue = parseExtra(tpl.Info)
+	}
+
+	return tpl, queue, nil
+}
+
+/*
+fixtureInfo decodes A2S_INFO saved to JSON.
+
+The a2s package encodes the format, server type and environment by their names
+and cannot decode them back, so the names are decoded here. The Ship data is not restored.
+*/
+type fixtureInfo struct {
+	a2s.Info
+	TheShip     json.RawMessage `json:"the_ship,omitempty"`
+	Format      string          `json:"format"`
+	ServerType  string          `json:"type"`
+	Environment string          `json:"environment"`
+}
+
+// info returns the decoded A2S_INFO with the named values restored, nil for offline server
+func (f *fixtureInfo) info() *a2s.Info {
+	if f == nil {
+		return nil
+	}
+
+	info := f.Info
+	info.Format = enumByName[a2s.InfoFormat](f.Format)
+	info.ServerType = enumByName[a2s.ServerType](f.ServerType)
+	info.Environment = enumByName[a2s.Environment](f.Environment)
+
+	return &info
+}
+
+// enumByName returns the first byte value of the enum with the name, zero if not found
+func enumByName[T interface {
+	~byte
+	String() string
+}](name string) T {
+	for b := range 256 {
+		if v := T(b); v.String() == name {
+			return v
+		}
+	}
+
+	return 0
+}
+
+// saveFixture writes the query result of the template data to the JSON file
+func (t *TemplateData) saveFixture(path string) error {
+	data, err := json.MarshalIndent(Fixture{
+		Info:    t.Info,
+		Players: t.Players,
+		Rules:   t.Rules,
+		Mods:    t.Mods,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal fixture: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write fixture: %w", err)
+	}
+
+	return nil
+}
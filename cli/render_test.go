@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/woozymasta/a2s/pkg/a2s"
+)
+
+func TestParseRenderArgs(t *testing.T) {
+	prev := configFile
+	t.Cleanup(func() { configFile = prev })
+
+	id, fixture, save, err := parseRenderArgs([]string{"cherno", "-f", "in.json", "--save", "out.json", "config.yaml"})
+	if err != nil || id != "cherno" || fixture != "in.json" || save != "out.json" || configFile != "config.yaml" {
+		t.Errorf("args = %q %q %q %q, %v", id, fixture, save, configFile, err)
+	}
+
+	if _, _, _, err := parseRenderArgs([]string{"cherno", "--fixture"}); err == nil {
+		t.Error("expected error for --fixture without path")
+	}
+}
+
+func TestRenderItemPrint(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		cut  bool
+		want []string // Substrings of the output
+		not  string   // Substring which must not be in the output
+	}{
+		{"ascii", "hello", false, []string{"(5/10 characters)"}, "warning"},
+		// Ten two-byte characters are within the limit of characters, only the byte cut applies
+		{"cyrillic name", strings.Repeat("ж", 10), false, []string{"(10/10 characters, 20 bytes)"}, "warning"},
+		{"long name", strings.Repeat("a", 11), false, []string{"warning: longer than the limit of 10 characters, Discord may reject it"}, ""},
+		{"cut topic", strings.Repeat("ж", 10), true, []string{"(10/10 characters, 20 bytes)", "the bot sends it cut to:\n  жжж..."}, ""},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		item := renderItem{name: "item", tmpl: template.Must(template.New("").Parse(tt.text)), limit: 10, cut: tt.cut}
+		item.print(&out)
+
+		for _, want := range tt.want {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("%s: output does not contain %q:\n%s", tt.name, want, out.String())
+			}
+		}
+		if tt.not != "" && strings.Contains(out.String(), tt.not) {
+			t.Errorf("%s: output contains %q:\n%s", tt.name, tt.not, out.String())
+		}
+	}
+}
+
+func TestRenderFixture(t *testing.T) {
+	dir := t.TempDir()
+	useConfig(t, `
+bot:
+  token: x
+servers:
+  - id: srv
+    channel_name: "{{ .Info.Players }}/{{ .Info.MaxPlayers }} {{ .Extra.Shard }}"
+`)
+
+	fixture := filepath.Join(dir, "srv.json")
+	data := `{"info": {"name": "DayZ", "players": 5, "max_players": 60, "id": 221100, "keywords": ["shard123", "lqs2"]}}`
+	if err := os.WriteFile(fixture, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		history.delete("srv")
+		uptimes.delete("srv")
+	})
+
+	var out bytes.Buffer
+	if err := render(&out, "srv", fixture, ""); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "channel_name (8/100 characters):\n  5/60 123") {
+		t.Errorf("output:\n%s", out.String())
+	}
+
+	if err := render(&out, "missing", fixture, ""); err == nil {
+		t.Error("expected error for unknown server")
+	}
+}
+
+func TestFixtureRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "srv.json")
+	srv := &ServerConfig{ID: "srv"}
+
+	// Enums are saved by name and restored to the first value with that name
+	saved := &TemplateData{Info: &a2s.Info{Name: "Test", Map: "map", ID: 221100, Format: 0x49, ServerType: 'd', Environment: 'w', Keywords: []string{"lqs2"}}}
+	if err := saved.saveFixture(path); err != nil {
+		t.Fatal(err)
+	}
+
+	tpl, queue, err := srv.loadFixture(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := tpl.Info
+	if info.Name != "Test" || info.Format.String() != "Source" || info.ServerType.String() != "Dedicated" || info.Environment.String() != "Windows" {
+		t.Errorf("info = %+v", info)
+	}
+	if tpl.Extra == nil || queue != 2 {
+		t.Errorf("extra = %v, queue = %d", tpl.Extra, queue)
+	}
+
+	// Offline server is saved without info
+	if err := (&TemplateData{}).saveFixture(path); err != nil {
+		t.Fatal(err)
+	}
+	if tpl, _, err = srv.loadFixture(path); err != nil || tpl.Info != nil {
+		t.Errorf("info = %+v, %v, expected offline server", tpl.Info, err)
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultReportPeriod = 24 * time.Hour // Period of the report if not set
+	maxMessageEmbeds    = 10             // Discord limit of embeds in one message
+)
+
+/*
+Report represents the configuration of a scheduled summary report.
+
+On schedule the report is posted to the text channel as one embed per server
+with statistics of the period ending at the time of the report.
+*/
+type Report struct {
+	ChannelID string        `yaml:"channel_id"`        // Discord text channel ID for the report
+	Schedule  string        `yaml:"schedule"`          // Cron-like schedule in local time, e.g. "0 9 * * *" or @daily
+	Title     string        `yaml:"title,omitempty"`   // Text posted above the embeds, e.g. "Daily report"
+	Servers   []string      `yaml:"servers,omitempty"` // IDs of servers in the report, all if empty
+	Period    time.Duration `yaml:"period,omitempty"`  // Period of the statistics, 24h if not set
+
+	schedule *cronSchedule // Parsed Schedule
+}
+
+/*
+PeriodStats represents the statistics of the server samples within a period.
+
+Players statistics are calculated from online samples only, the uptime is taken
+from the uptime tracker with hourly precision, the same as /uptime. An outage is a run
+of at least alerts.failures consecutive failed queries, the same way as alerts
+declare it, it lasts until the next successful query or the end of the period.
+The order of queries within downsampled samples is unknown, so outages are
+counted from single queries only, that is within history.raw_retention.
+*/
+type PeriodStats struct {
+	HistoryWindow
+	Uptime        float64       // Percent of successful queries from the uptime tracker
+	Queries       int           // Number of queries
+	Outages       int           // Number of outages
+	LongestOutage time.Duration // Duration of the longest outage
+	Downsampled   bool          // Period has downsampled samples, outages within them are not counted
+}
+
+/*
+validate parses the schedule and checks the channel and that all server IDs exist.
+
+Without history.data_dir the period must not be longer than the in-memory history.
+*/
+func (r *Report) validate(ids map[string]struct{}, h *HistoryConfig) error {
+	if r.ChannelID == "" {
+		return fmt.Errorf("channel_id is empty")
+	}
+	if r.Period < 0 {
+		return fmt.Errorf("period must not be negative")
+	}
+	if r.period() > uptimeRetention {
+		return fmt.Errorf("period must not be longer than %s of tracked uptime", uptimeRetention)
+	}
+	if !h.keeps(r.period()) {
+		return fmt.Errorf("period %s needs history.data_dir, the in-memory history keeps only %s", r.period(), h.retention())
+	}
+	for _, id := range r.Servers {
+		if _, ok := ids[id]; !ok {
+			return fmt.Errorf("unknown server id %q", id)
+		}
+	}
+
+	schedule, err := parseCron(r.Schedule)
+	if err != nil {
+		return err
+	}
+	r.schedule = schedule
+
+	return nil
+}
+
+// period returns the period of the statistics
+func (r *Report) period() time.Duration {
+	if r.Period > 0 {
+		return r.Period
+	}
+
+	return defaultReportPeriod
+}
+
+/*
+runReports posts the reports of the active configuration when their schedule matches.
+
+Schedules are checked at the start of every minute, so reports follow reloads.
+*/
+func runReports(ds *discordgo.Session) {
+	for {
+		now := time.Now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+		time.Sleep(next.Sub(now))
+
+		cfg := activeConfig.Load()
+		for i := range cfg.Reports {
+			if r := &cfg.Reports[i]; r.schedule.matches(next) {
+				go r.post(ds, cfg, next)
+			}
+		}
+	}
+}
+
+// post builds the embeds of the servers for the period ending at the time and sends them in batches
+func (r *Report) post(ds *discordgo.Session, cfg *Config, to time.Time) {
+	from := to.Add(-r.period())
+
+	var embeds []*discordgo.MessageEmbed
+	for _, srv := range cfg.Servers {
+		if len(r.Servers) > 0 && !slices.Contains(r.Servers, srv.ID) {
+			continue
+		}
+
+		samples, err := samplesSince(srv.ID, from)
+		if err != nil {
+			log.Error().Err(err).Str("server", srv.ID).Msg("Failed to read samples for report")
+			continue
+		}
+
+		stats := periodStats(samples, from, to, cfg.Alerts.Failures)
+		stats.Uptime = uptimes.percent(srv.ID, from, to)
+		embeds = append(embeds, stats.reportEmbed(srv.ID, from, to))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), discordTimeout)
+	defer cancel()
+
+	content := r.Title
+	for start := 0; start < len(embeds); start += maxMessageEmbeds {
+		msg := &discordgo.MessageSend{
+			Content: content,
+			Embeds:  embeds[start:min(start+maxMessageEmbeds, len(embeds))],
+		}
+		if _, err := ds.ChannelMessageSendComplex(r.ChannelID, msg, discordgo.WithContext(ctx)); err != nil {
+			log.Error().Err(err).Str("channel", r.ChannelID).Msg("Failed to send report")
+			return
+		}
+		content = ""
+	}
+
+	log.Info().Str("channel", r.ChannelID).Int("servers", len(embeds)).Msg("Report sent")
+}
+
+// periodStats calculates the statistics of samples from the start to the end of the period except the uptime
+func periodStats(samples []Sample, from, to time.Time, failures int) *PeriodStats {
+	st := &PeriodStats{}
+	st.calculate(samples, from)
+
+	var run int
+	var start time.Time
+	end := func(at time.Time) {
+		if run >= failures {
+			st.Outages++
+			st.LongestOutage = max(st.LongestOutage, at.Sub(start).Truncate(time.Second))
+		}
+		run = 0
+	}
+
+	for _, s := range samples {
+		if s.Time.Before(from) || s.Time.After(to) {
+			continue
+		}
+
+		st.Queries += s.count()
+
+		switch {
+		case s.Count > 0:
+			st.Downsampled = true
+			run = 0
+		case !s.Online:
+			if run == 0 {
+				start = s.Time
+			}
+			run++
+		case run > 0:
+			end(s.Time)
+		}
+	}
+	if run > 0 {
+		end(to)
+	}
+
+	return st
+}
+
+// reportEmbed creates the report embed of the server, times are shown in the time zone of the reader
+func (st *PeriodStats) reportEmbed(id string, from, to time.Time) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title:       id,
+		Description: fmt.Sprintf("<t:%d:f> — <t:%d:f>", from.Unix(), to.Unix()),
+		Color:       colorOnline,
+	}
+
+	if st.Queries == 0 {
+		embed.Description += "\nNo data for the period"
+		embed.Color = colorPartial
+		return embed
+	}
+
+	peak := "-"
+	if st.Samples > 0 {
+		peak = fmt.Sprintf("%d at <t:%d:f>", st.Peak, st.PeakTime.Unix())
+	}
+	longest := "-"
+	if st.Outages > 0 {
+		longest = st.LongestOutage.String()
+	}
+
+	switch {
+	case st.Samples == 0:
+		embed.Color = colorOffline
+	case st.Outages > 0:
+		embed.Color = colorPartial
+	}
+
+	embed.Fields = []*discordgo.MessageEmbedField{
+		{Name: "Peak players", Value: peak, Inline: true},
+		{Name: "Average players", Value: fmt.Sprintf("%.1f", st.Avg), Inline: true},
+		{Name: "Uptime", Value: fmt.Sprintf("%.2f%%", st.Uptime), Inline: true},
+		{Name: "Outages", Value: fmt.Sprintf("%d", st.Outages), Inline: true},
+		{Name: "Longest outage", Value: longest, Inline: true},
+	}
+	if st.Downsampled {
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: "Outages are counted within the raw retention of samples only"}
+	}
+
+	return embed
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeriodStats(t *testing.T) {
+	from := time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(10 * time.Minute)
+	at := func(minute int) time.Time { return from.Add(time.Duration(minute) * time.Minute) }
+	up := func(minute, players int) Sample { return Sample{Time: at(minute), Online: true, Players: players} }
+	down := func(minute int) Sample { return Sample{Time: at(minute)} }
+
+	tests := []struct {
+		name    string
+		samples []Sample
+		want    PeriodStats
+	}{
+		{
+			name: "no samples",
+		},
+		{
+			name:    "online",
+			samples: []Sample{up(0, 2), up(1, 6), up(2, 4)},
+			want:    PeriodStats{HistoryWindow: HistoryWindow{PeakTime: at(1), Avg: 4, Peak: 6, Min: 2, Samples: 3}, Queries: 3},
+		},
+		{
+			name:    "failures below the threshold",
+			samples: []Sample{up(0, 1), down(1), up(2, 1), down(3)},
+			want:    PeriodStats{HistoryWindow: HistoryWindow{PeakTime: at(0), Avg: 1, Peak: 1, Min: 1, Samples: 2}, Queries: 4},
+		},
+		{
+			name:    "outages",
+			samples: []Sample{up(0, 1), down(1), down(2), up(3, 1), down(4), down(5), down(6), up(7, 1)},
+			want: PeriodStats{
+				HistoryWindow: HistoryWindow{PeakTime: at(0), Avg: 1, Peak: 1, Min: 1, Samples: 3},
+				Queries:       8, Outages: 2, LongestOutage: 3 * time.Minute,
+			},
+		},
+		{
+			name:    "outage until the end of the period",
+			samples: []Sample{up(0, 1), down(6), down(7)},
+			want: PeriodStats{
+				HistoryWindow: HistoryWindow{PeakTime: at(0), Avg: 1, Peak: 1, Min: 1, Samples: 1},
+				Queries:       3, Outages: 1, LongestOutage: 4 * time.Minute,
+			},
+		},
+		{
+			name:    "samples outside the period",
+			samples: []Sample{down(-2), down(-1), up(0, 3), down(11), down(12)},
+			want:    PeriodStats{HistoryWindow: HistoryWindow{PeakTime: at(0), Avg: 3, Peak: 3, Min: 3, Samples: 1}, Queries: 1},
+		},
+		{
+			name: "downsampled",
+			samples: []Sample{
+				{Time: at(0), Online: true, Players: 2, Peak: 5, Count: 4, Up: 2},
+				{Time: at(5), Count: 4},
+				down(8), down(9),
+			},
+			want: PeriodStats{
+				HistoryWindow: HistoryWindow{PeakTime: at(0), Avg: 2, Peak: 5, Min: 2, Samples: 2},
+				Queries:       10, Outages: 1, LongestOutage: 2 * time.Minute, Downsampled: true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := periodStats(tt.samples, from, to, 2)
+			if *got != tt.want {
+				t.Errorf("stats = %+v\nexpected %+v", *got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	rotateEachServer  = "*"             // Server value to repeat the rotation template for every server
+	minRotateInterval = 5 * time.Second // Shorter intervals are throttled by Discord anyway
+)
+
+/*
+PresenceRotation is one template of the rotating Rich Presence.
+
+Without server the template is rendered with SummaryData like the online/offline templates,
+with a server ID it is rendered with the TemplateData of that server, and with "*"
+it is repeated for every server in configuration order.
+*/
+type PresenceRotation struct {
+	Server       string `yaml:"server,omitempty"`        // Server ID, "*" for every server, empty for all servers at once
+	Template     string `yaml:"template"`                // Template for the presence text
+	TemplateFile string `yaml:"template_file,omitempty"` // File with template for the presence text
+
+	tpl *template.Template // Compiled Template
+}
+
+// presenceSlide is a rotation template bound to its data
+type presenceSlide struct {
+	data any                // SummaryData or TemplateData of the server
+	tpl  *template.Template // Compiled template for the presence text
+}
+
+/*
+PresenceRotator cycles the Rich Presence through the rotation templates.
+
+It runs on its own interval independent of update_interval and renders
+the latest cached query results, so rotation never queries the servers.
+*/
+type PresenceRotator struct {
+	index int  // Index of the next slide
+	shown bool // At least one presence was set
+	mu    sync.Mutex
+}
+
+/*
+run switches the presence to the next slide every rotate_interval.
+
+The interval is read from the active configuration on every tick, so it follows reloads.
+Nothing is done while rotation is not configured.
+*/
+func (r *PresenceRotator) run(b *presenceBot) {
+	p, _ := b.config(activeConfig.Load())
+	interval := p.RotateInterval
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		p, servers := b.config(activeConfig.Load())
+		if p.RotateInterval != interval {
+			interval = p.RotateInterval
+			ticker.Reset(interval)
+		}
+
+		if len(p.Rotate) == 0 {
+			continue
+		}
+
+		data := dataCache.summary()
+		if data == nil {
+			continue
+		}
+
+		if err := r.next(b, p, data.subset(servers)); err != nil {
+			log.Error().Err(err).Str("bot", b.name).Msg("Error updating rotating Rich Presence")
+		}
+	}
+}
+
+// started reports whether the rotator has already set a presence
+func (r *PresenceRotator) started() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.shown
+}
+
+// next sets the presence to the next slide rendered to non-empty text
+func (r *PresenceRotator) next(b *presenceBot, p *Presence, data *SummaryData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := b.sender.set(b.ds, r.advance(p, data)); err != nil {
+		return err
+	}
+	r.shown = true
+
+	return nil
+}
+
+/*
+advance renders the next slide and moves the index past it, must be called with lock held.
+
+Slides rendered to an empty string are skipped, e.g. to hide offline servers,
+if all of them are empty the online/offline template is used.
+*/
+func (r *PresenceRotator) advance(p *Presence, data *SummaryData) discordgo.UpdateStatusData {
+	slides := p.slides(data)
+
+	for n := range slides {
+		i := (r.index + n) % len(slides)
+		if text := p.renderText(slides[i].tpl, slides[i].data, "rotation"); text != "" {
+			r.index = (i + 1) % len(slides)
+			return p.statusData(data.Stats, text)
+		}
+	}
+
+	return p.makeUSD(data)
+}
+
+// slides expands the rotation templates with their data, servers missing in data are skipped
+func (p *Presence) slides(data *SummaryData) []presenceSlide {
+	slides := make([]presenceSlide, 0, len(p.Rotate))
+
+	for _, rot := range p.Rotate {
+		switch rot.Server {
+		case "":
+			slides = append(slides, presenceSlide{data: data, tpl: rot.tpl})
+
+		case rotateEachServer:
+			for _, tpl := range data.Servers {
+				slides = append(slides, presenceSlide{data: tpl, tpl: rot.tpl})
+			}
+
+		default:
+			for _, tpl := range data.Servers {
+				if tpl.ID == rot.Server {
+					slides = append(slides, presenceSlide{data: tpl, tpl: rot.tpl})
+					break
+				}
+			}
+		}
+	}
+
+	return slides
+}
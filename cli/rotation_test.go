@@ -0,0 +1,58 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/woozymasta/a2s/pkg/a2s"
+)
+
+func TestPresenceRotation(t *testing.T) {
+	p := testPresence(t, Presence{Rotate: []PresenceRotation{
+		{Template: "{{ .Stats.Players }} players"},
+		{Server: rotateEachServer, Template: "{{ if .Info }}{{ .ID }} {{ .Info.Map }}{{ end }}"},
+		{Server: "b", Template: "only {{ .ID }}"},
+		{Server: "missing", Template: "never"},
+	}})
+
+	servers := []*TemplateData{
+		{ID: "a", Info: &a2s.Info{Map: "chernarusplus", Players: 4}},
+		{ID: "b"},
+	}
+	data := &SummaryData{Stats: statsOf(servers), Servers: servers}
+
+	// Offline server "b" renders empty text in the "*" rotation and is skipped
+	var r PresenceRotator
+	var got []string
+	for range 5 {
+		got = append(got, r.advance(p, data).Activities[0].Name)
+	}
+
+	want := []string{"4 players", "a chernarusplus", "only b", "4 players", "a chernarusplus"}
+	if !slices.Equal(got, want) {
+		t.Errorf("rotation = %q, expected %q", got, want)
+	}
+}
+
+func TestPresenceRotationEmpty(t *testing.T) {
+	p := testPresence(t, Presence{Rotate: []PresenceRotation{{Server: rotateEachServer, Template: "{{ if .Info }}{{ .ID }}{{ end }}"}}})
+	data := &SummaryData{Stats: &PresenceStats{Servers: 1}, Servers: []*TemplateData{{ID: "a"}}}
+
+	// All slides are empty, the offline template is used
+	var r PresenceRotator
+	if usd := r.advance(p, data); usd.Activities[0].Name != "Server offline" || usd.Status != "idle" {
+		t.Errorf("presence = %+v, expected the offline template", usd)
+	}
+}
+
+func TestPresenceTextCut(t *testing.T) {
+	p := testPresence(t, Presence{Rotate: []PresenceRotation{{Template: strings.Repeat("ж", maxPresenceText)}}})
+
+	var r PresenceRotator
+	text := r.advance(p, &SummaryData{Stats: &PresenceStats{}}).Activities[0].Name
+	if len(text) > maxPresenceText || !utf8.ValidString(text) || !strings.HasSuffix(text, "...") {
+		t.Errorf("text %q is not cut to %d bytes at a rune boundary", text, maxPresenceText)
+	}
+}
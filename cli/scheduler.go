@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+)
+
+// Discord allows only two name/topic edits per channel per 10 minutes
+const (
+	channelEditLimit  = 2
+	channelEditWindow = 10 * time.Minute
+)
+
+// channelEdit is a rendered channel/category edit waiting to be sent
+type channelEdit struct {
+	server      string // Server ID, used only for logs
+	name        string // New channel name
+	description string // New channel topic
+	hash        uint64 // Hash of the rendered name and topic
+	category    bool   // Edit of the server category, not the channel
+}
+
+// channelSlot holds the edit state of one channel
+type channelSlot struct {
+	pending  *channelEdit // Newest edit waiting for the budget
+	inFlight *channelEdit // Edit being sent now
+	edits    []time.Time  // Times of the successful edits within the window
+	blocked  time.Time    // Time until the channel is rate limited by Retry-After
+	applied  uint64       // Hash of the last successfully applied edit
+}
+
+/*
+Scheduler sends channel edits respecting Discord's per-channel rename rate limit.
+
+Each channel has its own edit budget, while the budget is exhausted only the newest
+rendered edit is kept and sent when the budget frees up. Rate limits from Discord
+(Retry-After) block only the affected channel, edits of other channels continue.
+*/
+type Scheduler struct {
+	apply   func(ctx context.Context, server, id, name, description string) error // Sends the edit to Discord
+	slots   map[string]*channelSlot
+	wake    chan struct{}
+	sem     chan struct{}
+	timeout time.Duration
+	mu      sync.Mutex
+}
+
+// channelScheduler is the global scheduler of channel and category edits
+var channelScheduler = &Scheduler{
+	slots: make(map[string]*channelSlot),
+	wake:  make(chan struct{}, 1),
+}
+
+/*
+start launches the scheduler loop, edits are sent by up to 'workerCount' concurrent requests.
+
+Each request uses a context with timeout to avoid being stuck if Discord is slow.
+*/
+func (s *Scheduler) start(ds *discordgo.Session, workerCount int, timeout time.Duration) {
+	s.mu.Lock()
+	s.apply = func(ctx context.Context, server, id, name, description string) error {
+		return editChannel(ctx, botSession(ds, activeConfig.Load(), server), id, name, description)
+	}
+	s.sem = make(chan struct{}, workerCount)
+	s.timeout = timeout
+	s.mu.Unlock()
+
+	go s.run()
+}
+
+/*
+submit schedules the edit of the channel, replacing the previous pending edit.
+
+Edits equal to the last applied or in-flight value are skipped.
+*/
+func (s *Scheduler) submit(id string, edit *channelEdit) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	slot := s.slot(id)
+	switch {
+	case edit.hash == slot.applied && slot.inFlight == nil:
+		slot.pending = nil
+		log.Debug().Str("channel", id).Msg("Skipping update for channel without changes detected")
+		return
+
+	case slot.inFlight != nil && slot.inFlight.hash == edit.hash:
+		slot.pending = nil
+		return
+
+	case slot.pending != nil && slot.pending.hash == edit.hash:
+		return
+	}
+
+	if slot.pending != nil {
+		log.Debug().Str("channel", id).Msg("Replacing pending channel update with newer one")
+	}
+	slot.pending = edit
+
+	s.notify()
+}
+
+// pending returns the number of edits waiting to be sent
+func (s *Scheduler) pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int
+	for _, slot := range s.slots {
+		if slot.pending != nil {
+			count++
+		}
+	}
+
+	return count
+}
+
+// run dispatches pending edits when the budget of their channel allows and sleeps until the next one
+func (s *Scheduler) run() {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		next := s.dispatch()
+
+		wait := time.Hour
+		if !next.IsZero() {
+			wait = time.Until(next)
+		}
+		timer.Reset(wait)
+
+		select {
+		case <-s.wake:
+		case <-timer.C:
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+	}
+}
+
+// dispatch starts sending of all edits allowed now and returns the earliest time of the next allowed edit
+func (s *Scheduler) dispatch() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var next time.Time
+
+	for id, slot := range s.slots {
+		if slot.pending == nil || slot.inFlight != nil {
+			continue
+		}
+
+		if at := slot.allowedAt(now); at.After(now) {
+			if next.IsZero() || at.Before(next) {
+				next = at
+			}
+			continue
+		}
+
+		// All workers busy, a finished worker wakes the scheduler
+		select {
+		case s.sem <- struct{}{}:
+		default:
+			return next
+		}
+
+		slot.inFlight, slot.pending = slot.pending, nil
+		go s.send(id, slot.inFlight)
+	}
+
+	return next
+}
+
+// send performs the edit and updates the channel budget by the result
+func (s *Scheduler) send(id string, edit *channelEdit) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	log.Debug().
+		Str("channel", id).
+		Str("server", edit.server).
+		Uint64("hash", edit.hash).
+		Msg("Updating channel")
+
+	err := s.apply(ctx, edit.server, id, edit.name, edit.description)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.notify()
+	<-s.sem
+
+	// The channel was removed from configuration while the edit was sent
+	slot, ok := s.slots[id]
+	if !ok {
+		return
+	}
+	slot.inFlight = nil
+	now := time.Now()
+
+	var rlErr *discordgo.RateLimitError
+	switch {
+	case err == nil:
+		slot.applied = edit.hash
+		slot.edits = append(slot.pruneEdits(now), now)
+		botState.setChannel(edit.server, edit.category, id, edit.hash, slot.edits)
+
+	case errors.As(err, &rlErr):
+		slot.blocked = now.Add(rlErr.RetryAfter)
+		if slot.pending == nil {
+			slot.pending = edit
+		}
+		log.Warn().
+			Str("channel", id).
+			Str("server", edit.server).
+			Dur("retry_after", rlErr.RetryAfter).
+			Msg("Discord rate limit hit, channel update postponed")
+
+	default:
+		log.Error().Err(err).Str("channel", id).Str("server", edit.server).Msg("Failed to update channel")
+	}
+}
+
+// restore sets the applied hash and recent edits of the channel loaded from the state file
+func (s *Scheduler) restore(id string, hash uint64, edits []time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	slot := s.slot(id)
+	slot.applied = hash
+	slot.edits = append([]time.Time(nil), edits...)
+}
+
+/*
+prune removes the state of channels not in the set, so edits of channels removed
+from configuration by reload are not sent anymore.
+*/
+func (s *Scheduler) prune(ids map[string]struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id := range s.slots {
+		if _, ok := ids[id]; !ok {
+			delete(s.slots, id)
+			log.Debug().Str("channel", id).Msg("Removed state of channel no longer configured")
+		}
+	}
+}
+
+// slot returns the state of the channel, creating it if needed, must be called with lock held
+func (s *Scheduler) slot(id string) *channelSlot {
+	slot, ok := s.slots[id]
+	if !ok {
+		slot = &channelSlot{}
+		s.slots[id] = slot
+	}
+
+	return slot
+}
+
+// notify wakes the scheduler loop without blocking
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// allowedAt returns the earliest time the next edit of the channel can be sent
+func (c *channelSlot) allowedAt(now time.Time) time.Time {
+	at := c.blocked
+
+	edits := c.pruneEdits(now)
+	if len(edits) >= channelEditLimit {
+		if free := edits[len(edits)-channelEditLimit].Add(channelEditWindow); free.After(at) {
+			at = free
+		}
+	}
+
+	return at
+}
+
+// pruneEdits returns the edits made within the window
+func (c *channelSlot) pruneEdits(now time.Time) []time.Time {
+	edits := c.edits[:0]
+	for _, t := range c.edits {
+		if now.Sub(t) < channelEditWindow {
+			edits = append(edits, t)
+		}
+	}
+	c.edits = edits
+
+	return edits
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// fakeEdits records the edits sent by the scheduler and returns the configured error
+type fakeEdits struct {
+	sent []string // Names of sent edits in order
+	err  error
+	mu   sync.Mutex
+}
+
+func (f *fakeEdits) apply(_ context.Context, _, _, name, _ string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, name)
+
+	return f.err
+}
+
+func (f *fakeEdits) names() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string(nil), f.sent...)
+}
+
+// newTestScheduler returns the scheduler sending edits to the fake without the run loop
+func newTestScheduler(workers int, f *fakeEdits) *Scheduler {
+	return &Scheduler{
+		apply:   f.apply,
+		slots:   make(map[string]*channelSlot),
+		wake:    make(chan struct{}, 1),
+		sem:     make(chan struct{}, workers),
+		timeout: time.Second,
+	}
+}
+
+// waitIdle waits until no edit is in flight
+func waitIdle(t *testing.T, s *Scheduler) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		busy := false
+		for _, slot := range s.slots {
+			busy = busy || slot.inFlight != nil
+		}
+		s.mu.Unlock()
+
+		if !busy {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatal("edits are still in flight")
+}
+
+func testEdit(name string) *channelEdit {
+	return &channelEdit{name: name, hash: uint64(len(name))<<8 | uint64(name[0])}
+}
+
+func TestSchedulerBudget(t *testing.T) {
+	f := &fakeEdits{}
+	s := newTestScheduler(2, f)
+
+	for _, name := range []string{"a", "bb", "ccc"} {
+		s.submit("1", testEdit(name))
+		next := s.dispatch()
+		waitIdle(t, s)
+
+		if name == "ccc" {
+			// Two edits within the window exhaust the budget, the third waits for the first to expire
+			first := s.slots["1"].edits[0]
+			if want := first.Add(channelEditWindow); !next.Equal(want) {
+				t.Errorf("next = %s, expected %s", next, want)
+			}
+			continue
+		}
+		if !next.IsZero() {
+			t.Errorf("next = %s after %q, expected zero", next, name)
+		}
+	}
+
+	if got := f.names(); len(got) != 2 || got[0] != "a" || got[1] != "bb" {
+		t.Errorf("sent = %q, expected a, bb", got)
+	}
+	if s.pending() != 1 {
+		t.Errorf("pending = %d, expected 1", s.pending())
+	}
+
+	// The budget frees up once the oldest edit leaves the window
+	s.slots["1"].edits[0] = time.Now().Add(-channelEditWindow)
+	s.dispatch()
+	waitIdle(t, s)
+
+	if got := f.names(); len(got) != 3 || got[2] != "ccc" {
+		t.Errorf("sent = %q, expected ccc last", got)
+	}
+}
+
+func TestSchedulerCoalescing(t *testing.T) {
+	f := &fakeEdits{}
+	s := newTestScheduler(1, f)
+
+	now := time.Now()
+	s.restore("1", testEdit("applied").hash, []time.Time{now, now})
+
+	s.submit("1", testEdit("a"))
+	s.submit("1", testEdit("bb"))
+	if s.pending() != 1 || s.slots["1"].pending.name != "bb" {
+		t.Fatalf("pending = %+v, expected only the newest edit", s.slots["1"].pending)
+	}
+
+	// An edit equal to the applied one drops the pending edit
+	s.submit("1", testEdit("applied"))
+	if s.pending() != 0 {
+		t.Errorf("pending = %d, expected 0", s.pending())
+	}
+
+	s.dispatch()
+	waitIdle(t, s)
+	if got := f.names(); len(got) != 0 {
+		t.Errorf("sent = %q, expected nothing", got)
+	}
+}
+
+func TestSchedulerRetryAfter(t *testing.T) {
+	f := &fakeEdits{err: &discordgo.RateLimitError{RateLimit: &discordgo.RateLimit{
+		TooManyRequests: &discordgo.TooManyRequests{RetryAfter: time.Minute},
+	}}}
+	s := newTestScheduler(1, f)
+
+	s.submit("1", testEdit("a"))
+	s.dispatch()
+	waitIdle(t, s)
+
+	slot := s.slots["1"]
+	if slot.pending == nil || slot.pending.name != "a" {
+		t.Fatalf("pending = %+v, expected the rate limited edit", slot.pending)
+	}
+	if len(slot.edits) != 0 || slot.applied != 0 {
+		t.Errorf("rate limited edit counted as applied")
+	}
+
+	next := s.dispatch()
+	if d := time.Until(next); d <= 0 || d > time.Minute {
+		t.Errorf("next in %s, expected within Retry-After", d)
+	}
+
+	// Other channels are not blocked
+	f.mu.Lock()
+	f.err = nil
+	f.mu.Unlock()
+	s.submit("2", testEdit("bb"))
+	s.dispatch()
+	waitIdle(t, s)
+
+	if got := f.names(); len(got) != 2 || got[1] != "bb" {
+		t.Errorf("sent = %q, expected bb last", got)
+	}
+}
+
+func TestSchedulerFailedEdit(t *testing.T) {
+	f := &fakeEdits{err: errors.New("failed")}
+	s := newTestScheduler(1, f)
+
+	s.submit("1", testEdit("a"))
+	s.dispatch()
+	waitIdle(t, s)
+
+	slot := s.slots["1"]
+	if slot.pending != nil || len(slot.edits) != 0 || slot.applied != 0 {
+		t.Errorf("slot = %+v, expected failed edit to be dropped without using the budget", slot)
+	}
+}
+
+func TestSchedulerWorkers(t *testing.T) {
+	release := make(chan struct{})
+	f := &fakeEdits{}
+	s := newTestScheduler(1, f)
+	s.apply = func(ctx context.Context, server, id, name, description string) error {
+		<-release
+		return f.apply(ctx, server, id, name, description)
+	}
+
+	s.submit("1", testEdit("a"))
+	s.submit("2", testEdit("bb"))
+	s.dispatch()
+
+	s.mu.Lock()
+	var inFlight int
+	for _, slot := range s.slots {
+		if slot.inFlight != nil {
+			inFlight++
+		}
+	}
+	s.mu.Unlock()
+
+	if inFlight != 1 {
+		t.Errorf("in flight = %d, expected 1 with one worker", inFlight)
+	}
+	if s.pending() != 1 {
+		t.Errorf("pending = %d, expected 1", s.pending())
+	}
+
+	close(release)
+	waitIdle(t, s)
+	s.dispatch()
+	waitIdle(t, s)
+
+	if got := f.names(); len(got) != 2 {
+		t.Errorf("sent = %q, expected both edits", got)
+	}
+}
+
+func TestSchedulerPrune(t *testing.T) {
+	f := &fakeEdits{}
+	s := newTestScheduler(1, f)
+
+	now := time.Now()
+	s.restore("1", 1, []time.Time{now, now})
+	s.submit("1", testEdit("a"))
+	s.submit("2", testEdit("bb"))
+
+	s.prune(map[string]struct{}{"2": {}})
+
+	if _, ok := s.slots["1"]; ok {
+		t.Error("slot of removed channel is kept")
+	}
+	if s.pending() != 1 {
+		t.Errorf("pending = %d, expected 1", s.pending())
+	}
+
+	s.dispatch()
+	waitIdle(t, s)
+	if got := f.names(); len(got) != 1 || got[0] != "bb" {
+		t.Errorf("sent = %q, expected only bb", got)
+	}
+}
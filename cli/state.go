@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// stateSaveDelay groups state changes made close together into one write
+const stateSaveDelay = 2 * time.Second
+
+/*
+State is the change-detection state persisted between restarts.
+
+It stores per server ID the hashes and times of the last successful channel and
+category edits, the posted status message and the last known server status,
+so a restart does not re-edit every channel and burn the rate limit.
+
+Rich Presence is not persisted, Discord drops it with the gateway session,
+so it must be set again after every restart anyway.
+*/
+type State struct {
+	Servers       map[string]*ServerState `json:"servers"`                  // State of every server by server ID
+	StatusMessage *MessageState           `json:"status_message,omitempty"` // Status message for all servers
+
+	path  string        // Path to the state file
+	dirty chan struct{} // Signals the state was changed and must be saved
+	mu    sync.Mutex
+}
+
+// ServerState is the persisted state of one server
+type ServerState struct {
+	Channel       *ChannelState `json:"channel,omitempty"`        // Last applied channel edit
+	Category      *ChannelState `json:"category,omitempty"`       // Last applied category edit
+	StatusMessage *MessageState `json:"status_message,omitempty"` // Posted status message
+	FailedSince   time.Time     `json:"failed_since,omitzero"`    // Time of the first failed query in a row
+	Failures      int           `json:"failures,omitempty"`       // Number of consecutive failed queries
+	Status        serverState   `json:"status"`                   // Last known server status
+	Alerted       bool          `json:"alerted,omitempty"`        // Offline alert of the current outage was sent
+}
+
+// ChannelState is the last applied edit of a channel or category
+type ChannelState struct {
+	ID    string      `json:"id"`              // Discord channel ID
+	Edits []time.Time `json:"edits,omitempty"` // Times of the successful edits within the rate limit window
+	Hash  uint64      `json:"hash"`            // Hash of the applied name and topic
+}
+
+// MessageState is the posted status message
+type MessageState struct {
+	ChannelID string `json:"channel_id"` // Discord channel ID
+	MessageID string `json:"message_id"` // Discord message ID
+	Hash      uint64 `json:"hash"`       // Hash of the embed
+}
+
+// botState is the global persisted state, nil if the state file is not configured
+var botState *State
+
+/*
+loadState reads the state file and starts saving changes to it in background.
+
+A missing file is not an error, the state starts empty and the file is created on first change.
+*/
+func loadState(path string) (*State, error) {
+	state := &State{
+		Servers: make(map[string]*ServerState),
+		path:    path,
+		dirty:   make(chan struct{}, 1),
+	}
+
+	data, err := os.ReadFile(path) // #nosec G304
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, state); err != nil {
+			return nil, fmt.Errorf("failed to parse state file: %w", err)
+		}
+		if state.Servers == nil {
+			state.Servers = make(map[string]*ServerState)
+		}
+	case errors.Is(err, fs.ErrNotExist):
+		log.Debug().Str("path", path).Msg("State file not found, starting with empty state")
+	default:
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	go state.run()
+
+	return state, nil
+}
+
+/*
+restore applies the loaded state to the configuration and the channel scheduler.
+
+Channel state is restored only if the channel ID is the same, the status message
+only if the channel is the same and no other message_id is configured.
+*/
+func (st *State) restore(cfg *Config) {
+	if st == nil {
+		return
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	cfg.StatusMessage.restore(st.StatusMessage)
+
+	for i := range cfg.Servers {
+		srv := &cfg.Servers[i]
+		ss, ok := st.Servers[srv.ID]
+		if !ok {
+			continue
+		}
+
+		srv.state = ss.Status
+		srv.failures = ss.Failures
+		srv.failedSince = ss.FailedSince
+		srv.alerted = ss.Alerted
+
+		if ss.Channel != nil && ss.Channel.ID == srv.ChannelID {
+			channelScheduler.restore(ss.Channel.ID, ss.Channel.Hash, ss.Channel.Edits)
+		}
+		if ss.Category != nil && ss.Category.ID == srv.CategoryID {
+			channelScheduler.restore(ss.Category.ID, ss.Category.Hash, ss.Category.Edits)
+		}
+
+		srv.StatusMessage.restore(ss.StatusMessage)
+	}
+
+	log.Info().Str("path", st.path).Int("servers", len(st.Servers)).Msg("State restored")
+}
+
+// setServerStatus stores the last known status of the server
+func (st *State) setServerStatus(s *ServerConfig) {
+	if st == nil {
+		return
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	ss := st.server(s.ID)
+	if ss.Status == s.state && ss.Failures == s.failures && ss.FailedSince.Equal(s.failedSince) && ss.Alerted == s.alerted {
+		return
+	}
+
+	ss.Status = s.state
+	ss.Failures = s.failures
+	ss.FailedSince = s.failedSince
+	ss.Alerted = s.alerted
+	st.markDirty()
+}
+
+// setChannel stores the last applied edit of the server channel or category
+func (st *State) setChannel(server string, category bool, id string, hash uint64, edits []time.Time) {
+	if st == nil || server == "" {
+		return
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	cs := &ChannelState{ID: id, Hash: hash, Edits: append([]time.Time(nil), edits...)}
+	if category {
+		st.server(server).Category = cs
+	} else {
+		st.server(server).Channel = cs
+	}
+	st.markDirty()
+}
+
+// setMessage stores the posted status message of the server, empty server ID means the message for all servers
+func (st *State) setMessage(server string, m *MessageState) {
+	if st == nil {
+		return
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if server == "" {
+		st.StatusMessage = m
+	} else {
+		st.server(server).StatusMessage = m
+	}
+	st.markDirty()
+}
+
+// deleteServer removes the state of the server removed from configuration
+func (st *State) deleteServer(id string) {
+	if st == nil {
+		return
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if _, ok := st.Servers[id]; ok {
+		delete(st.Servers, id)
+		st.markDirty()
+	}
+}
+
+// server returns the state of the server, creating it if needed, must be called with lock held
+func (st *State) server(id string) *ServerState {
+	ss, ok := st.Servers[id]
+	if !ok {
+		ss = &ServerState{}
+		st.Servers[id] = ss
+	}
+
+	return ss
+}
+
+// markDirty signals the saver without blocking, must be called with lock held
+func (st *State) markDirty() {
+	select {
+	case st.dirty <- struct{}{}:
+	default:
+	}
+}
+
+// run saves the state after changes, changes made within stateSaveDelay are saved together
+func (st *State) run() {
+	for range st.dirty {
+		time.Sleep(stateSaveDelay)
+		if err := st.save(); err != nil {
+			log.Error().Err(err).Str("path", st.path).Msg("Failed to save state")
+		}
+	}
+}
+
+// save writes the state atomically, to a temporary file renamed over the state file
+func (st *State) save() error {
+	if st == nil {
+		return nil
+	}
+
+	st.mu.Lock()
+	data, err := json.MarshalIndent(st, "", "  ")
+	st.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	if err := writeFileAtomic(st.path, data); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	log.Debug().Str("path", st.path).Msg("State saved")
+	return nil
+}
+
+/*
+fatal saves the state and returns the fatal log event.
+
+Fatal event exits the process without running deferred functions, so the state
+changed after startup would be lost without saving it first.
+*/
+func fatal() *zerolog.Event {
+	if err := botState.save(); err != nil {
+		log.Error().Err(err).Msg("Error saving state")
+	}
+
+	return log.Fatal()
+}
+
+// restore applies the persisted message to the status message if the channel is the same
+func (m *StatusMessage) restore(ms *MessageState) {
+	if m == nil || ms == nil || ms.ChannelID != m.ChannelID {
+		return
+	}
+	if m.MessageID != "" && m.MessageID != ms.MessageID {
+		return
+	}
+
+	m.MessageID = ms.MessageID
+	m.prevHash = ms.Hash
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	st, err := loadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st.Servers) != 0 {
+		t.Fatalf("servers = %d, expected empty state without file", len(st.Servers))
+	}
+
+	failedSince := time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+	edits := []time.Time{failedSince.Add(time.Minute)}
+	st.setServerStatus(&ServerConfig{ID: "srv", state: stateOffline, failures: 3, failedSince: failedSince, alerted: true})
+	st.setChannel("srv", false, "10", 42, edits)
+	st.setChannel("srv", true, "11", 43, nil)
+	st.setMessage("srv", &MessageState{ChannelID: "12", MessageID: "13", Hash: 44})
+	st.setMessage("", &MessageState{ChannelID: "14", MessageID: "15", Hash: 45})
+	st.setServerStatus(&ServerConfig{ID: "removed"})
+	st.deleteServer("removed")
+
+	if err := st.save(); err != nil {
+		t.Fatal(err)
+	}
+	if matches, _ := filepath.Glob(path + ".*.tmp"); len(matches) != 0 {
+		t.Errorf("temporary files are left: %q", matches)
+	}
+
+	loaded, err := loadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{
+		StatusMessage: &StatusMessage{ChannelID: "14"},
+		Servers: []ServerConfig{{
+			ID:            "srv",
+			ChannelID:     "10",
+			CategoryID:    "99", // Category changed, its state is not restored
+			StatusMessage: &StatusMessage{ChannelID: "12"},
+		}},
+	}
+	loaded.restore(cfg)
+
+	srv := cfg.Servers[0]
+	if srv.state != stateOffline || srv.failures != 3 || !srv.failedSince.Equal(failedSince) || !srv.alerted {
+		t.Errorf("server = %s, %d failures since %s, alerted %t", srv.state, srv.failures, srv.failedSince, srv.alerted)
+	}
+	if srv.StatusMessage.MessageID != "13" || srv.StatusMessage.prevHash != 44 {
+		t.Errorf("server message = %q, %d", srv.StatusMessage.MessageID, srv.StatusMessage.prevHash)
+	}
+	if cfg.StatusMessage.MessageID != "15" || cfg.StatusMessage.prevHash != 45 {
+		t.Errorf("message = %q, %d", cfg.StatusMessage.MessageID, cfg.StatusMessage.prevHash)
+	}
+	if _, ok := loaded.Servers["removed"]; ok {
+		t.Error("removed server is saved")
+	}
+	if ch := loaded.Servers["srv"].Channel; ch == nil || ch.Hash != 42 || len(ch.Edits) != 1 || !ch.Edits[0].Equal(edits[0]) {
+		t.Errorf("channel = %+v", ch)
+	}
+}
+
+func TestLoadStateBroken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte("{broken"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := loadState(path); err == nil {
+		t.Error("expected error for broken state file")
+	}
+}
+
+func TestNilState(t *testing.T) {
+	var st *State
+	st.restore(&Config{})
+	st.setServerStatus(&ServerConfig{ID: "srv"})
+	st.setChannel("srv", false, "1", 1, nil)
+	st.setMessage("", &MessageState{})
+	st.deleteServer("srv")
+	if err := st.save(); err != nil {
+		t.Error(err)
+	}
+}
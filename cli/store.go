@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	storeCompactInterval = time.Hour // Interval of applying retention and downsampling to stored samples
+	storeSamplesDir      = "samples" // Subdirectory of data_dir with sample files
+	storeSamplesExt      = ".jsonl"  // Extension of sample files
+)
+
+/*
+SampleStore stores the samples of every server on disk.
+
+Every server has its own append-only file with one JSON sample per line in
+data_dir/samples. The files are compacted at startup and every hour: samples
+older than retention are dropped and samples older than raw_retention are
+downsampled to one sample per resolution interval. Files of servers removed
+from the configuration are kept until their samples expire.
+*/
+type SampleStore struct {
+	dir string     // Directory with sample files
+	mu  sync.Mutex // Serializes appends and compaction
+}
+
+// sampleStore is the global sample store, nil if history.data_dir is not configured
+var sampleStore *SampleStore
+
+// openSampleStore creates the samples directory, compacts existing files and starts compacting them in background
+func openSampleStore(cfg *HistoryConfig) (*SampleStore, error) {
+	dir := filepath.Join(cfg.DataDir, storeSamplesDir)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create samples directory: %w", err)
+	}
+
+	st := &SampleStore{dir: dir}
+	st.compactAll(cfg)
+	go st.run()
+
+	log.Info().Str("path", dir).Msg("Sample store opened")
+	return st, nil
+}
+
+// path returns the file of the server samples, the server ID is escaped to be a safe file name
+func (st *SampleStore) path(id string) string {
+	return filepath.Join(st.dir, url.QueryEscape(id)+storeSamplesExt)
+}
+
+// append writes the sample to the end of the server file
+func (st *SampleStore) append(id string, s Sample) {
+	if st == nil {
+		return
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		log.Error().Err(err).Str("server", id).Msg("Failed to marshal sample")
+		return
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	f, err := os.OpenFile(st.path(id), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640) // #nosec G304
+	if err != nil {
+		log.Error().Err(err).Str("server", id).Msg("Failed to open samples file")
+		return
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		log.Error().Err(err).Str("server", id).Msg("Failed to write sample")
+	}
+	if err := f.Close(); err != nil {
+		log.Error().Err(err).Str("server", id).Msg("Failed to close samples file")
+	}
+}
+
+// read returns the stored samples of the server since the time, oldest first
+func (st *SampleStore) read(id string, since time.Time) ([]Sample, error) {
+	if st == nil {
+		return nil, nil
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	samples, err := readSamples(st.path(id))
+	if err != nil {
+		return nil, err
+	}
+
+	for i, s := range samples {
+		if !s.Time.Before(since) {
+			return samples[i:], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// readSamples reads all samples of the file, a missing file has no samples and broken lines are skipped
+func readSamples(path string) ([]Sample, error) {
+	f, err := os.Open(path) // #nosec G304
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open samples file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var samples []Sample
+	var broken int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var s Sample
+		if err := json.Unmarshal(scanner.Bytes(), &s); err != nil {
+			broken++
+			continue
+		}
+		samples = append(samples, s)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read samples file: %w", err)
+	}
+
+	if broken > 0 {
+		log.Warn().Str("path", path).Int("lines", broken).Msg("Skipped broken lines of samples file")
+	}
+
+	return samples, nil
+}
+
+// run compacts the sample files every storeCompactInterval with the active configuration
+func (st *SampleStore) run() {
+	ticker := time.NewTicker(storeCompactInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if cfg := activeConfig.Load(); cfg != nil {
+			st.compactAll(&cfg.History)
+		}
+	}
+}
+
+// compactAll compacts the files of all servers in the samples directory
+func (st *SampleStore) compactAll(cfg *HistoryConfig) {
+	paths, err := filepath.Glob(filepath.Join(st.dir, "*"+storeSamplesExt))
+	if err != nil {
+		log.Error().Err(err).Str("path", st.dir).Msg("Failed to list samples files")
+		return
+	}
+
+	for _, path := range paths {
+		if err := st.compact(path, cfg, time.Now()); err != nil {
+			log.Error().Err(err).Str("path", path).Msg("Failed to compact samples file")
+		}
+	}
+}
+
+/*
+compact applies retention and downsampling to the file and rewrites it atomically.
+
+A file without samples left is removed.
+*/
+func (st *SampleStore) compact(path string, cfg *HistoryConfig, now time.Time) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	samples, err := readSamples(path)
+	if err != nil {
+		return err
+	}
+
+	kept := downsample(samples, now.Add(-cfg.Retention), now.Add(-cfg.RawRetention), cfg.Resolution)
+	if len(kept) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove expired samples file: %w", err)
+		}
+		return nil
+	}
+	if len(kept) == len(samples) {
+		return nil
+	}
+
+	var b strings.Builder
+	for _, s := range kept {
+		data, err := json.Marshal(s)
+		if err != nil {
+			return fmt.Errorf("failed to marshal sample: %w", err)
+		}
+		b.Write(data)
+		b.WriteByte('\n')
+	}
+
+	if err := writeFileAtomic(path, []byte(b.String())); err != nil {
+		return err
+	}
+
+	log.Debug().Str("path", path).Int("before", len(samples)).Int("after", len(kept)).Msg("Samples file compacted")
+	return nil
+}
+
+/*
+downsample drops samples before the expiry and merges samples before the raw
+limit into one sample per resolution interval.
+
+Samples must be sorted by time, as they are appended.
+*/
+func downsample(samples []Sample, expiry, raw time.Time, resolution time.Duration) []Sample {
+	kept := make([]Sample, 0, len(samples))
+
+	for _, s := range samples {
+		if s.Time.Before(expiry) {
+			continue
+		}
+		if !s.Time.Before(raw) {
+			kept = append(kept, s)
+			continue
+		}
+
+		bucket := s.Time.Truncate(resolution)
+		if n := len(kept); n > 0 && kept[n-1].Count > 0 && kept[n-1].Time.Equal(bucket) {
+			kept[n-1].merge(s)
+			continue
+		}
+
+		agg := Sample{Time: bucket}
+		agg.merge(s)
+		kept = append(kept, agg)
+	}
+
+	return kept
+}
+
+// merge adds the raw or downsampled sample to the downsampled one
+func (s *Sample) merge(o Sample) {
+	count, up := s.Count, s.Up
+	oCount, oUp := o.count(), o.up()
+
+	// Averages are calculated from online samples only, as the statistics of windows
+	if up+oUp > 0 {
+		s.Players = (s.Players*up + o.Players*oUp + (up+oUp)/2) / (up + oUp)
+		s.Latency = (s.Latency*time.Duration(up) + o.Latency*time.Duration(oUp)) / time.Duration(up+oUp)
+	}
+
+	s.Peak = max(s.Peak, o.peak())
+	s.MaxPlayers = max(s.MaxPlayers, o.MaxPlayers)
+	s.Queue = max(s.Queue, o.Queue)
+	if o.Map != "" {
+		s.Map = o.Map
+	}
+
+	s.Count = count + oCount
+	s.Up = up + oUp
+	s.Online = s.Up > 0
+}
+
+// writeFileAtomic writes the data to a temporary file renamed over the path
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to sync temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDownsample(t *testing.T) {
+	base := time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+	at := func(m int) time.Time { return base.Add(time.Duration(m) * time.Minute) }
+	online := func(m, players int) Sample {
+		return Sample{Time: at(m), Online: true, Players: players, Latency: time.Duration(players) * time.Millisecond}
+	}
+
+	tests := []struct {
+		name    string
+		samples []Sample
+		want    []Sample
+	}{
+		{
+			"expired and raw",
+			[]Sample{online(-1, 5), online(0, 6), online(30, 7), online(31, 8)},
+			// Sample at the expiry is kept, sample at the raw limit is not downsampled
+			[]Sample{{Time: at(0), Online: true, Players: 6, Peak: 6, Latency: 6 * time.Millisecond, Count: 1, Up: 1}, online(30, 7), online(31, 8)},
+		},
+		{
+			"weighted by online samples",
+			[]Sample{online(0, 10), {Time: at(1)}, online(2, 21), online(10, 4)},
+			[]Sample{
+				{Time: at(0), Online: true, Players: 16, Peak: 21, Latency: 15500 * time.Microsecond, Count: 3, Up: 2},
+				{Time: at(10), Online: true, Players: 4, Peak: 4, Latency: 4 * time.Millisecond, Count: 1, Up: 1},
+			},
+		},
+		{
+			"offline bucket",
+			[]Sample{{Time: at(0)}, {Time: at(5)}},
+			[]Sample{{Time: at(0), Count: 2}},
+		},
+		{
+			"downsampled again",
+			[]Sample{
+				{Time: at(0), Online: true, Players: 10, Peak: 12, MaxPlayers: 60, Queue: 1, Map: "a", Count: 3, Up: 1},
+				{Time: at(5), Online: true, Players: 20, Peak: 25, MaxPlayers: 50, Queue: 3, Map: "b", Count: 3, Up: 3},
+			},
+			[]Sample{{Time: at(0), Online: true, Players: 18, Peak: 25, MaxPlayers: 60, Queue: 3, Map: "b", Count: 6, Up: 4}},
+		},
+	}
+
+	for _, tt := range tests {
+		got := downsample(tt.samples, at(0), at(30), 10*time.Minute)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s:\n got %+v\nwant %+v", tt.name, got, tt.want)
+		}
+
+		// Compaction of compacted samples changes nothing
+		if again := downsample(got, at(0), at(30), 10*time.Minute); !slices.Equal(again, got) {
+			t.Errorf("%s: downsampled again %+v", tt.name, again)
+		}
+	}
+}
+
+func TestHistoryDownsampled(t *testing.T) {
+	at := time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+	samples := []Sample{
+		{Time: at, Online: true, Players: 10, Peak: 30, Count: 4, Up: 3},
+		{Time: at.Add(time.Hour), Count: 2},
+		{Time: at.Add(2 * time.Hour), Online: true, Players: 2},
+	}
+
+	// Downsampled samples weigh by online queries and report their peak
+	var w HistoryWindow
+	w.calculate(samples, at)
+	if w.Peak != 30 || !w.PeakTime.Equal(at) || w.Min != 2 || w.Samples != 4 || w.Avg != 8 {
+		t.Errorf("window = %+v", w)
+	}
+}
+
+func TestSampleStore(t *testing.T) {
+	cfg := &HistoryConfig{DataDir: t.TempDir(), Resolution: time.Hour, Retention: 48 * time.Hour, RawRetention: 2 * time.Hour}
+	st, err := openSampleStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Server ID is escaped to a file name
+	const id = "dayz/1"
+	now := time.Now().Truncate(time.Hour)
+	samples := []Sample{
+		{Time: now.Add(-72 * time.Hour), Online: true, Players: 1},
+		{Time: now.Add(-5 * time.Hour), Online: true, Players: 10},
+		{Time: now.Add(-5*time.Hour + time.Minute), Online: true, Players: 20},
+		{Time: now.Add(-time.Hour), Online: true, Players: 30},
+	}
+	for _, s := range samples {
+		st.append(id, s)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.DataDir, storeSamplesDir, "dayz%2F1.jsonl")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Store opened again compacts the file
+	if st, err = openSampleStore(cfg); err != nil {
+		t.Fatal(err)
+	}
+	got, err := st.read(id, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Players != 15 || got[0].Peak != 20 || got[0].Count != 2 || !got[1].Time.Equal(samples[3].Time) {
+		t.Errorf("samples = %+v", got)
+	}
+
+	if got, _ = st.read(id, now.Add(-2*time.Hour)); len(got) != 1 {
+		t.Errorf("samples since = %+v", got)
+	}
+
+	// Broken lines are skipped, a file with expired samples only is removed
+	path := st.path("old")
+	if err := os.WriteFile(path, []byte("{broken\n{\"time\":\"2000-01-01T00:00:00Z\"}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = readSamples(path); err != nil || len(got) != 1 {
+		t.Errorf("samples = %+v, %v", got, err)
+	}
+	st.compactAll(cfg)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expired file is kept: %v", err)
+	}
+
+	if got, err = (*SampleStore)(nil).read(id, time.Time{}); got != nil || err != nil {
+		t.Errorf("nil store = %+v, %v", got, err)
+	}
+}
+
+func TestHistorySeed(t *testing.T) {
+	st := &SampleStore{dir: t.TempDir()}
+	cfg := historyConfig(2, "24h")
+	cfg.Bot.UpdateInterval = time.Minute
+	cfg.Servers = []ServerConfig{{ID: "up"}, {ID: "stale"}}
+
+	now := time.Now()
+	st.append("up", Sample{Time: now.Add(-time.Hour), Online: true, Players: 4})
+	st.append("up", Sample{Time: now.Add(-time.Minute), Online: true, Players: 8})
+	st.append("stale", Sample{Time: now.Add(-time.Hour), Online: true, Players: 1})
+
+	h := &HistoryStore{servers: make(map[string]*serverHistory)}
+	h.seed(cfg, st)
+
+	if got := h.record("up", Sample{Time: now, Online: true, Players: 6}, cfg); got.Windows["24h"].Samples != 3 || !got.UpSince.Equal(now.Add(-time.Hour)) {
+		t.Errorf("history = %+v, up since %s", got.Windows["24h"], got.UpSince)
+	}
+
+	// Last stored sample older than alerts.failures intervals does not continue the uptime
+	if got := h.record("stale", Sample{Time: now, Online: true}, cfg); !got.UpSince.Equal(now) {
+		t.Errorf("up since %s, expected %s", got.UpSince, now)
+	}
+}
+
+func TestHistoryConfigStore(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  HistoryConfig
+		err  bool
+	}{
+		{"store", HistoryConfig{DataDir: "data", Resolution: time.Minute, Retention: time.Hour, RawRetention: time.Hour}, false},
+		{"without resolution", HistoryConfig{DataDir: "data", Retention: time.Hour}, true},
+		{"retention below raw", HistoryConfig{DataDir: "data", Resolution: time.Minute, Retention: time.Hour, RawRetention: 2 * time.Hour}, true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.cfg.validate(); (err != nil) != tt.err {
+			t.Errorf("%s: error = %v", tt.name, err)
+		}
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file")
+
+	for _, data := range []string{"first", "second"} {
+		if err := writeFileAtomic(path, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := os.ReadFile(path); err != nil || string(got) != data {
+			t.Errorf("file = %q, %v", got, err)
+		}
+	}
+
+	// Temporary files are not left behind
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory has %d entries", len(entries))
+	}
+
+	if err := writeFileAtomic(filepath.Join(dir, "missing", "file"), nil); err == nil {
+		t.Error("expected error for missing directory")
+	}
+}
@@ -3,8 +3,11 @@
 package main
 
 import (
+	"maps"
 	"math"
 	"reflect"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"text/template"
@@ -12,50 +15,114 @@ import (
 	"unicode"
 
 	"github.com/woozymasta/a2s/pkg/a2s"
+	"github.com/woozymasta/a2s/pkg/a3sb"
 	"github.com/woozymasta/steam/utils/appid"
 )
 
 /*
 TemplateData represents the data passed to templates for rendering.
 
-It includes server information, extra data, and server connection details.
+It includes server information, extra data, player list, rules, and server connection details.
 */
 type TemplateData struct {
-	Info  *a2s.Info // Server information from A2S
-	Extra any       // Additional arbitrary data
-	ID    string    // Server identifier
-	Host  string    // Server host address
-	Port  int       // Server port
+	Info    *a2s.Info         // Server information from A2S
+	Extra   any               // Additional arbitrary data
+	History *History          // Historical data of the server
+	Uptime  *Uptime           // Availability of the server within 24h, 7d and 30d
+	Rules   map[string]string // Server rules from A2S_RULES (only with query_rules)
+	ID      string            // Server identifier
+	Host    string            // Server host address
+	Players []a2s.Player      // Players from A2S_PLAYER (only with query_players)
+	Mods    []a3sb.Mod        // Mods decoded from Arma 3/DayZ rules (only with query_rules)
+	Port    int               // Server port
+
+	playersQueried bool // A2S_PLAYER succeeded, Players is the full list
 }
 
 /*
-render applies the template string to the TemplateData.
+SummaryData represents the data passed to templates rendered for all servers at once.
 
-It parses the provided template string, applies the data,
-and returns the resulting string or an error if the process fails.
+It includes the aggregated statistics and the template data of every server.
 */
-func (t *TemplateData) render(tplStr string) (string, error) {
-	funcMap := template.FuncMap{
-		"AppID":           tplHelperAppIDtoString,
-		"DurationEmoji":   tplHelperDurationEmoji,
-		"TimeEmoji":       tplHelperTimeEmoji,
-		"OSEmoji":         tplHelperOSEmoji,
-		"CountryEmoji":    tplHelperCountryEmoji,
-		"CodeEmoji":       tplHelperCodeEmoji,
-		"ValueColorEmoji": tplHelperValueColorEmoji,
-		"RoundDown":       tplHelperRoundDownTo,
-		"RoundUp":         tplHelperRoundUpTo,
-		"Clamp":           tplHelperClamp,
+type SummaryData struct {
+	Stats   *PresenceStats  // Aggregated statistics of all servers
+	Servers []*TemplateData // Template data of every server in configuration order
+}
+
+// render applies the compiled template to the TemplateData.
+func (t *TemplateData) render(tmpl *template.Template) (string, error) {
+	return executeTemplate(tmpl, t)
+}
+
+// tplFuncMap holds the helper functions available in all templates
+var tplFuncMap = template.FuncMap{
+	"AppID":           tplHelperAppIDtoString,
+	"DurationEmoji":   tplHelperDurationEmoji,
+	"TimeEmoji":       tplHelperTimeEmoji,
+	"OSEmoji":         tplHelperOSEmoji,
+	"CountryEmoji":    tplHelperCountryEmoji,
+	"CodeEmoji":       tplHelperCodeEmoji,
+	"ValueColorEmoji": tplHelperValueColorEmoji,
+	"RoundDown":       tplHelperRoundDownTo,
+	"RoundUp":         tplHelperRoundUpTo,
+	"Clamp":           tplHelperClamp,
+	"TopPlayers":      tplHelperTopPlayers,
+	"LongestPlayers":  tplHelperLongestPlayers,
+	"Trend":           tplHelperTrend,
+	"EscapeMarkdown":  tplHelperEscapeMarkdown,
+}
+
+// markdownEscaper prefixes the characters of Discord markdown with a backslash
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "~", `\~`, "`", "\\`", "|", `\|`,
+	">", `\>`, "<", `\<`, "#", `\#`, "-", `\-`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`,
+)
+
+/*
+newTemplateBase creates the base template set with helper functions and shared named templates.
+
+Every configured template is parsed on a copy of this set, so named templates
+can be used in all of them with {{ template "name" . }}.
+*/
+func newTemplateBase(named map[string]string) (*template.Template, error) {
+	base := template.New("").Funcs(tplFuncMap)
+
+	for _, name := range slices.Sorted(maps.Keys(named)) {
+		if _, err := base.New(name).Parse(named[name]); err != nil {
+			return nil, err
+		}
 	}
 
-	tmpl, err := template.New("template").Funcs(funcMap).Parse(tplStr)
+	return base, nil
+}
+
+// compileTemplate parses the template on a copy of the base set, empty text means no template
+func compileTemplate(base *template.Template, name, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+
+	tmpl, err := base.Clone()
 	if err != nil {
-		return "⛔ template error", err
+		return nil, err
+	}
+
+	return tmpl.New(name).Parse(text)
+}
+
+/*
+executeTemplate applies the compiled template to the data.
+
+It returns the resulting string or an error if the execution fails,
+nil template renders to an empty string.
+*/
+func executeTemplate(tmpl *template.Template, data any) (string, error) {
+	if tmpl == nil {
+		return "", nil
 	}
 
 	var sb strings.Builder
-	err = tmpl.Execute(&sb, t)
-	if err != nil {
+	if err := tmpl.Execute(&sb, data); err != nil {
 		return "⚠️ template error", err
 	}
 
@@ -248,6 +315,40 @@ func tplHelperClamp(val, minVal, maxVal any) int64 {
 	return v
 }
 
+// tplHelperTopPlayers returns up to limit players sorted by score in descending order.
+func tplHelperTopPlayers(players []a2s.Player, limit any) []a2s.Player {
+	return sortPlayers(players, limit, func(a, b a2s.Player) bool {
+		return a.Score > b.Score
+	})
+}
+
+// tplHelperLongestPlayers returns up to limit players sorted by connection duration in descending order.
+func tplHelperLongestPlayers(players []a2s.Player, limit any) []a2s.Player {
+	return sortPlayers(players, limit, func(a, b a2s.Player) bool {
+		return a.Duration > b.Duration
+	})
+}
+
+// sortPlayers returns a sorted copy of players cut to limit, limit <= 0 means no limit
+func sortPlayers(players []a2s.Player, limit any, less func(a, b a2s.Player) bool) []a2s.Player {
+	sorted := make([]a2s.Player, len(players))
+	copy(sorted, players)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return less(sorted[i], sorted[j])
+	})
+
+	if n := int(toInt64(limit)); n > 0 && n < len(sorted) {
+		sorted = sorted[:n]
+	}
+
+	return sorted
+}
+
+// tplHelperEscapeMarkdown escapes Discord markdown in text chosen by users, like player names.
+func tplHelperEscapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
 // force parse numbers and strings to int or return 0 otherwise
 func toInt64(v any) int64 {
 	val := reflect.ValueOf(v)
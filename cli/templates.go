@@ -0,0 +1,237 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// templateExt is the extension of shared template files in templates_dir
+const templateExt = ".tmpl"
+
+/*
+compileTemplates compiles the shared named templates and all configured templates.
+
+Templates are named by their path in the configuration, so errors point to the
+option with the problem. All errors are collected and returned together.
+Default alert, player event and presence templates are compiled if the options are not set.
+*/
+func (c *Config) compileTemplates() error {
+	base, err := newTemplateBase(c.Templates)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	compile := func(name, text string) *template.Template {
+		tmpl, err := compileTemplate(base, name, text)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		return tmpl
+	}
+
+	for i := range c.Servers {
+		srv := &c.Servers[i]
+		path := "servers." + srv.ID
+
+		srv.tplChannelName = compile(path+".channel_name", srv.ChannelName)
+		srv.tplChannelDesc = compile(path+".channel_description", srv.ChannelDesc)
+		srv.tplCategoryName = compile(path+".category_name", srv.CategoryName)
+		srv.StatusMessage.compile(path+".status_message", compile)
+	}
+
+	c.StatusMessage.compile("status_message", compile)
+
+	c.Alerts.tplOffline = compile("alerts.offline_message", orDefault(c.Alerts.OfflineMessage, defaultOfflineMessage))
+	c.Alerts.tplOnline = compile("alerts.online_message", orDefault(c.Alerts.OnlineMessage, defaultOnlineMessage))
+
+	c.PlayerEvents.tplJoin = compile("player_events.join_message", orDefault(c.PlayerEvents.JoinMessage, defaultJoinMessage))
+	c.PlayerEvents.tplLeave = compile("player_events.leave_message", orDefault(c.PlayerEvents.LeaveMessage, defaultLeaveMessage))
+
+	c.Bot.Presence.compile("bot.presence", compile)
+	for i := range c.Bots {
+		c.Bots[i].Presence.compile(fmt.Sprintf("bots.%d.presence", i), compile)
+	}
+
+	return errors.Join(errs...)
+}
+
+// compile compiles the templates of the status message
+func (m *StatusMessage) compile(path string, compile func(name, text string) *template.Template) {
+	if m == nil {
+		return
+	}
+
+	m.tplTitle = compile(path+".title", m.Title)
+	m.tplDescription = compile(path+".description", m.Description)
+	m.tplColor = compile(path+".color", m.Color)
+	m.tplFooter = compile(path+".footer", m.Footer)
+
+	for i := range m.Fields {
+		f := &m.Fields[i]
+		f.tplName = compile(fmt.Sprintf("%s.fields.%d.name", path, i), f.Name)
+		f.tplValue = compile(fmt.Sprintf("%s.fields.%d.value", path, i), f.Value)
+	}
+}
+
+// compile compiles the presence templates, defaults are used for empty online/offline templates
+func (p *Presence) compile(path string, compile func(name, text string) *template.Template) {
+	p.tplOnline = compile(path+".online", orDefault(p.Online, defaultPresenceOnline))
+	p.tplOffline = compile(path+".offline", orDefault(p.Offline, defaultPresenceOffline))
+
+	for i := range p.Rotate {
+		r := &p.Rotate[i]
+		r.tpl = compile(fmt.Sprintf("%s.rotate.%d.template", path, i), r.Template)
+	}
+}
+
+/*
+readTemplateFiles reads the templates set by *_file options and the shared templates from templates_dir.
+
+Every *.tmpl file in templates_dir is a shared template named after the file without
+the extension. Relative *_file paths are resolved against templates_dir if it is set.
+The file content replaces the inline template, setting both is an error.
+Paths of all read files are kept to reload the configuration when they change.
+*/
+func (c *Config) readTemplateFiles() error {
+	r := &templateReader{dir: c.TemplatesDir}
+
+	if c.TemplatesDir != "" {
+		r.readDir(c)
+	}
+
+	for i := range c.Servers {
+		srv := &c.Servers[i]
+		path := "servers." + srv.ID
+
+		r.read(path+".channel_name", &srv.ChannelName, srv.ChannelNameFile)
+		r.read(path+".channel_description", &srv.ChannelDesc, srv.ChannelDescFile)
+		r.read(path+".category_name", &srv.CategoryName, srv.CategoryNameFile)
+		srv.StatusMessage.readFiles(path+".status_message", r)
+	}
+
+	c.StatusMessage.readFiles("status_message", r)
+
+	r.read("alerts.offline_message", &c.Alerts.OfflineMessage, c.Alerts.OfflineMessageFile)
+	r.read("alerts.online_message", &c.Alerts.OnlineMessage, c.Alerts.OnlineMessageFile)
+
+	r.read("player_events.join_message", &c.PlayerEvents.JoinMessage, c.PlayerEvents.JoinMessageFile)
+	r.read("player_events.leave_message", &c.PlayerEvents.LeaveMessage, c.PlayerEvents.LeaveMessageFile)
+
+	c.Bot.Presence.readFiles("bot.presence", r)
+	for i := range c.Bots {
+		c.Bots[i].Presence.readFiles(fmt.Sprintf("bots.%d.presence", i), r)
+	}
+
+	c.templateFiles = r.files
+
+	return errors.Join(r.errs...)
+}
+
+// readFiles reads the templates of the status message from files
+func (m *StatusMessage) readFiles(path string, r *templateReader) {
+	if m == nil {
+		return
+	}
+
+	r.read(path+".title", &m.Title, m.TitleFile)
+	r.read(path+".description", &m.Description, m.DescriptionFile)
+	r.read(path+".color", &m.Color, m.ColorFile)
+	r.read(path+".footer", &m.Footer, m.FooterFile)
+
+	for i := range m.Fields {
+		f := &m.Fields[i]
+		r.read(fmt.Sprintf("%s.fields.%d.name", path, i), &f.Name, f.NameFile)
+		r.read(fmt.Sprintf("%s.fields.%d.value", path, i), &f.Value, f.ValueFile)
+	}
+}
+
+// readFiles reads the presence templates from files
+func (p *Presence) readFiles(path string, r *templateReader) {
+	r.read(path+".online", &p.Online, p.OnlineFile)
+	r.read(path+".offline", &p.Offline, p.OfflineFile)
+
+	for i := range p.Rotate {
+		rot := &p.Rotate[i]
+		r.read(fmt.Sprintf("%s.rotate.%d.template", path, i), &rot.Template, rot.TemplateFile)
+	}
+}
+
+// templateReader reads template files and collects their paths and errors
+type templateReader struct {
+	dir   string   // Base directory for relative paths
+	files []string // Paths of read files
+	errs  []error  // Errors of all files
+}
+
+// read reads the template file into the text if the file is set
+func (r *templateReader) read(name string, text *string, file string) {
+	if file == "" {
+		return
+	}
+	if *text != "" {
+		r.errs = append(r.errs, fmt.Errorf("%s: both template and %s_file are set", name, name[strings.LastIndex(name, ".")+1:]))
+		return
+	}
+
+	if r.dir != "" && !filepath.IsAbs(file) {
+		file = filepath.Join(r.dir, file)
+	}
+
+	content, err := r.readFile(file)
+	if err != nil {
+		r.errs = append(r.errs, fmt.Errorf("%s: %w", name, err))
+		return
+	}
+
+	*text = content
+}
+
+// readDir reads all *.tmpl files of the directory as shared templates, inline ones with the same name are an error
+func (r *templateReader) readDir(c *Config) {
+	if _, err := os.Stat(r.dir); err != nil {
+		r.errs = append(r.errs, fmt.Errorf("templates_dir: %w", err))
+		return
+	}
+	r.files = append(r.files, r.dir)
+
+	paths, err := filepath.Glob(filepath.Join(r.dir, "*"+templateExt))
+	if err != nil {
+		r.errs = append(r.errs, fmt.Errorf("templates_dir: %w", err))
+		return
+	}
+
+	if c.Templates == nil {
+		c.Templates = make(map[string]string, len(paths))
+	}
+
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), templateExt)
+		if _, ok := c.Templates[name]; ok {
+			r.errs = append(r.errs, fmt.Errorf("templates_dir: template %q is also defined in templates", name))
+			continue
+		}
+
+		content, err := r.readFile(path)
+		if err != nil {
+			r.errs = append(r.errs, fmt.Errorf("templates_dir: %w", err))
+			continue
+		}
+		c.Templates[name] = content
+	}
+}
+
+// readFile reads the file without trailing line breaks and remembers its path
+func (r *templateReader) readFile(path string) (string, error) {
+	data, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return "", fmt.Errorf("failed to read template file: %w", err)
+	}
+	r.files = append(r.files, path)
+
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
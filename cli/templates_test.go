@@ -0,0 +1,136 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/woozymasta/a2s/pkg/a2s"
+)
+
+func TestCompileTemplates(t *testing.T) {
+	cfg := &Config{
+		Templates: map[string]string{
+			"players": "{{ .Info.Players }}/{{ .Info.MaxPlayers }}",
+			"name":    `{{ template "players" . }} {{ .Info.Map }}`,
+		},
+		Servers: []ServerConfig{{ID: "srv", ChannelName: `{{ template "name" . }}`}},
+	}
+	if err := cfg.compileTemplates(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := executeTemplate(cfg.Servers[0].tplChannelName, &TemplateData{Info: &a2s.Info{Players: 3, MaxPlayers: 60, Map: "livonia"}})
+	if err != nil || got != "3/60 livonia" {
+		t.Errorf("channel name = %q, %v", got, err)
+	}
+
+	// Not configured templates are nil, defaults are compiled for alerts, events and presence
+	if cfg.Servers[0].tplChannelDesc != nil {
+		t.Error("empty template is compiled")
+	}
+	if cfg.Alerts.tplOffline == nil || cfg.PlayerEvents.tplJoin == nil || cfg.Bot.Presence.tplOnline == nil {
+		t.Error("default templates are not compiled")
+	}
+}
+
+func TestCompileTemplatesErrors(t *testing.T) {
+	cfg := &Config{
+		Servers: []ServerConfig{{ID: "srv", ChannelName: "{{ .ID", CategoryName: `{{ template "missing" . }}`}},
+		Alerts:  Alerts{OnlineMessage: "{{ Unknown }}"},
+	}
+
+	// All errors are reported together with the path of the option
+	err := cfg.compileTemplates()
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{"servers.srv.channel_name", "alerts.online_message"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %s: %v", want, err)
+		}
+	}
+
+	// Missing named template fails at execution, not at compilation
+	if _, err := executeTemplate(cfg.Servers[0].tplCategoryName, &TemplateData{}); err == nil {
+		t.Error("expected error for missing named template")
+	}
+
+	if err := (&Config{Templates: map[string]string{"broken": "{{ if }}"}}).compileTemplates(); err == nil {
+		t.Error("expected error for broken shared template")
+	}
+}
+
+func TestCompiledTemplatesIsolated(t *testing.T) {
+	// Templates defined inside one option do not leak to the other ones
+	cfg := &Config{Servers: []ServerConfig{
+		{ID: "a", ChannelName: `{{ define "local" }}a{{ end }}{{ template "local" }}`},
+		{ID: "b", ChannelName: `{{ template "local" }}`},
+	}}
+	if err := cfg.compileTemplates(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := executeTemplate(cfg.Servers[0].tplChannelName, nil); err != nil || got != "a" {
+		t.Errorf("a = %q, %v", got, err)
+	}
+	if _, err := executeTemplate(cfg.Servers[1].tplChannelName, nil); err == nil {
+		t.Error("template defined in another option is available")
+	}
+}
+
+func TestReadTemplateFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	shared := write("players.tmpl", "{{ .Info.Players }}\n")
+	name := write("name.txt", "{{ template \"players\" . }} online\r\n")
+	abs := write("topic.txt", "{{ .ID }}")
+
+	cfg := &Config{
+		TemplatesDir: dir,
+		Servers:      []ServerConfig{{ID: "srv", ChannelNameFile: "name.txt", ChannelDescFile: abs}},
+	}
+	if err := cfg.readTemplateFiles(); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Templates["players"] != "{{ .Info.Players }}" {
+		t.Errorf("shared templates = %q", cfg.Templates)
+	}
+	if srv := cfg.Servers[0]; srv.ChannelName != `{{ template "players" . }} online` || srv.ChannelDesc != "{{ .ID }}" {
+		t.Errorf("channel templates = %q, %q", srv.ChannelName, srv.ChannelDesc)
+	}
+	for _, file := range []string{dir, shared, name, abs} {
+		if !slices.Contains(cfg.templateFiles, file) {
+			t.Errorf("file %s is not watched, watched %q", file, cfg.templateFiles)
+		}
+	}
+}
+
+func TestReadTemplateFilesErrors(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "players.tmpl"), []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]*Config{
+		"inline and file":   {Servers: []ServerConfig{{ID: "srv", ChannelName: "x", ChannelNameFile: "name.txt"}}},
+		"missing file":      {TemplatesDir: dir, Alerts: Alerts{OnlineMessageFile: "missing.txt"}},
+		"missing directory": {TemplatesDir: filepath.Join(dir, "missing")},
+		"duplicate shared":  {TemplatesDir: dir, Templates: map[string]string{"players": "y"}},
+	}
+
+	for name, cfg := range tests {
+		if err := cfg.readTemplateFiles(); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
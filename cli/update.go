@@ -5,26 +5,25 @@ package main
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/rs/zerolog/log"
-	"github.com/woozymasta/a2s/pkg/keywords"
-	"github.com/woozymasta/steam/utils/appid"
 )
 
 /*
 update performs the Rich Presence update and enqueues channel updates.
 
 Steps:
- 1. Query each server in parallel to get info.
+ 1. Query each server in parallel to get info, record and store its history.
  2. Update aggregated stats for Rich Presence.
- 3. Immediately update Rich Presence (fast).
- 4. Enqueue tasks to update channels/categories (async).
+ 3. Immediately update Rich Presence of every bot (fast).
+ 4. Enqueue tasks to update channels/categories/status messages (async).
+ 5. Update the status message of all servers (async).
 */
 func update(ds *discordgo.Session, cfg *Config) {
-	stats := &PresenceStats{Servers: len(cfg.Servers)}
+	results := make([]*TemplateData, len(cfg.Servers))
 
-	var mu sync.Mutex
 	var wg sync.WaitGroup
 	sem := make(chan struct{}, cfg.Bot.Concurrency)
 
@@ -36,47 +35,35 @@ func update(ds *discordgo.Session, cfg *Config) {
 			defer func() { <-sem }()
 
 			srv := &cfg.Servers[i]
-			tplData := &TemplateData{
-				ID:   srv.ID,
-				Host: srv.Host,
-				Port: srv.Port,
-			}
+			bot := botSession(ds, cfg, srv.ID)
 
 			log.Debug().
 				Str("server", srv.ID).
 				Str("host", fmt.Sprintf("%s:%d", srv.Host, srv.Port)).
 				Msg("Querying server")
 
-			info, err := srv.getInfo()
+			tplData, localQueue, err := srv.query()
+			sample := newSample(tplData, localQueue, time.Now())
+			tplData.History = history.record(srv.ID, sample, cfg)
+			tplData.Uptime = uptimes.record(srv.ID, sample)
+			sampleStore.append(srv.ID, sample)
+			results[i] = tplData
 			if err != nil {
 				log.Warn().Err(err).Str("server", srv.ID).Msgf("Failed to retrieve information for server")
 				// If server is offline, we still might want to update channel to "offline".
 				// Enqueue with nil Info
+				srv.trackState(bot, cfg, tplData)
+				dataCache.set(tplData)
+				observeServer(tplData, 0)
 				channelUpdateQueue <- ChannelUpdateTask{Server: srv, Tpl: tplData}
 				return
 			}
-			tplData.Info = info
-
-			// Parse extra keywords if needed
-			localQueue := 0
-			switch info.ID {
-			case appid.Arma3.Uint64():
-				armaInfo := keywords.ParseArma3(info.Keywords)
-				tplData.Extra = armaInfo
-
-			case appid.DayZ.Uint64(), appid.DayZExp.Uint64():
-				dayzInfo := keywords.ParseDayZ(info.Keywords)
-				localQueue = int(dayzInfo.PlayersQueue)
-				tplData.Extra = dayzInfo
-			}
 
-			// Aggregate stats
-			mu.Lock()
-			stats.Players += int(info.Players)
-			stats.Slots += int(info.MaxPlayers)
-			stats.Queue += localQueue
-			stats.OnlineServers++
-			mu.Unlock()
+			// Track online/offline transitions and players, cache the result for slash commands
+			srv.trackState(bot, cfg, tplData)
+			srv.trackPlayers(cfg, tplData)
+			dataCache.set(tplData)
+			observeServer(tplData, localQueue)
 
 			// Enqueue the update of channel/category asynchronously
 			channelUpdateQueue <- ChannelUpdateTask{Server: srv, Tpl: tplData}
@@ -85,10 +72,20 @@ func update(ds *discordgo.Session, cfg *Config) {
 
 	wg.Wait()
 
-	// Update Discord Rich Presence with aggregated stats (immediate)
-	if err := stats.update(ds, cfg); err != nil {
-		log.Error().Err(err).Msg("Error updating Rich Presence")
+	// Update Discord Rich Presence of every bot with aggregated stats (immediate)
+	summary := &SummaryData{Stats: statsOf(results), Servers: results}
+	dataCache.setSummary(summary)
+	for _, b := range presenceBots {
+		if err := b.update(cfg, summary); err != nil {
+			log.Error().Err(err).Str("bot", b.name).Msg("Error updating Rich Presence")
+		}
+	}
+
+	// Update the status message of all servers (async)
+	if cfg.StatusMessage != nil {
+		go cfg.StatusMessage.process(ds, summary, cfg.summaryEmbed, discordTimeout)
 	}
 
+	health.updated()
 	log.Info().Msg("Update completed")
 }
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	uptimeBucket    = time.Hour           // Interval of counting queries
+	uptimeRetention = 30 * 24 * time.Hour // Longest window of uptime
+)
+
+// uptimeWindows are the names of the uptime windows used in metrics and the /uptime command
+var uptimeWindows = []string{"24h", "7d", "30d"}
+
+/*
+Uptime represents the availability of the server passed to templates as .Uptime.
+
+Values are the percent of successful A2S_INFO queries within the window with
+hourly precision. Windows cover only the tracked period, see Since.
+*/
+type Uptime struct {
+	Since time.Time // Time of the first tracked query within 30 days, zero without queries
+	Day   float64   // Percent of successful queries within 24 hours
+	Week  float64   // Percent of successful queries within 7 days
+	Month float64   // Percent of successful queries within 30 days
+}
+
+// UptimeTracker counts successful and failed queries of every server in hourly buckets
+type UptimeTracker struct {
+	servers map[string][]uptimeCount
+	mu      sync.Mutex
+}
+
+// uptimeCount is the number of queries within one bucket
+type uptimeCount struct {
+	start   time.Time // Start of the bucket
+	first   time.Time // Time of the first query in the bucket
+	queries int       // Number of queries
+	up      int       // Number of successful queries
+}
+
+// uptimes tracks the availability of all servers
+var uptimes = &UptimeTracker{servers: make(map[string][]uptimeCount)}
+
+// record counts the sample of the server and returns its uptime for templates
+func (u *UptimeTracker) record(id string, s Sample) *Uptime {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.add(id, s)
+	up := u.calculate(id, s.Time)
+	observeUptime(id, up)
+
+	return up
+}
+
+/*
+seed counts the stored samples of all servers within 30 days.
+
+It is used at startup, so uptime continues after restart.
+*/
+func (u *UptimeTracker) seed(cfg *Config, store *SampleStore) {
+	if store == nil {
+		return
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for _, srv := range cfg.Servers {
+		samples, err := store.read(srv.ID, time.Now().Add(-uptimeRetention))
+		if err != nil {
+			log.Error().Err(err).Str("server", srv.ID).Msg("Failed to read stored samples")
+			continue
+		}
+
+		for _, s := range samples {
+			u.add(srv.ID, s)
+		}
+	}
+}
+
+// percent returns the percent of successful queries of the server within the period, zero without queries
+func (u *UptimeTracker) percent(id string, from, to time.Time) float64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	return uptimePercent(u.servers[id], from, to)
+}
+
+// get returns the uptime of the server, nil if no queries are tracked
+func (u *UptimeTracker) get(id string) *Uptime {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if len(u.servers[id]) == 0 {
+		return nil
+	}
+
+	return u.calculate(id, time.Now())
+}
+
+// delete removes the counts of the server removed from configuration
+func (u *UptimeTracker) delete(id string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	delete(u.servers, id)
+}
+
+// add counts the sample in its bucket and drops buckets older than 30 days, must be called with lock held
+func (u *UptimeTracker) add(id string, s Sample) {
+	counts := u.servers[id]
+	start := s.Time.Truncate(uptimeBucket)
+
+	if n := len(counts); n > 0 && counts[n-1].start.Equal(start) {
+		counts[n-1].queries += s.count()
+		counts[n-1].up += s.up()
+	} else {
+		counts = append(counts, uptimeCount{start: start, first: s.Time, queries: s.count(), up: s.up()})
+	}
+
+	cutoff := s.Time.Add(-uptimeRetention)
+	drop := 0
+	for drop < len(counts) && !counts[drop].start.Add(uptimeBucket).After(cutoff) {
+		drop++
+	}
+	u.servers[id] = slices.Delete(counts, 0, drop)
+}
+
+// calculate returns the uptime of the server for windows ending at the time, must be called with lock held
+func (u *UptimeTracker) calculate(id string, now time.Time) *Uptime {
+	counts := u.servers[id]
+	result := &Uptime{}
+	if len(counts) > 0 {
+		result.Since = counts[0].first
+	}
+
+	end := now.Truncate(uptimeBucket).Add(uptimeBucket)
+	result.Day = uptimePercent(counts, now.Add(-24*time.Hour), end)
+	result.Week = uptimePercent(counts, now.Add(-7*24*time.Hour), end)
+	result.Month = uptimePercent(counts, now.Add(-uptimeRetention), end)
+
+	return result
+}
+
+// uptimePercent returns the percent of successful queries in buckets overlapping the period from the time until the time
+func uptimePercent(counts []uptimeCount, from, to time.Time) float64 {
+	var queries, up int
+	for _, c := range counts {
+		if c.start.Add(uptimeBucket).After(from) && c.start.Before(to) {
+			queries += c.queries
+			up += c.up
+		}
+	}
+	if queries == 0 {
+		return 0
+	}
+
+	return float64(up) * 100 / float64(queries)
+}
+
+// windows returns the uptime values in the order of uptimeWindows
+func (u *Uptime) windows() []float64 {
+	return []float64{u.Day, u.Week, u.Month}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUptimeTracker(t *testing.T) {
+	u := &UptimeTracker{servers: make(map[string][]uptimeCount)}
+	start := time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+	// One query every 10 minutes for two days, offline on the second day from 06:00 to 12:00
+	var last *Uptime
+	for at := start; at.Before(start.Add(48 * time.Hour)); at = at.Add(10 * time.Minute) {
+		offline := at.Sub(start) >= 30*time.Hour && at.Sub(start) < 36*time.Hour
+		last = u.record("a", Sample{Time: at, Online: !offline})
+	}
+
+	if !last.Since.Equal(start) {
+		t.Errorf("since = %s, expected %s", last.Since, start)
+	}
+	// The window starts within the bucket of 23:00, which is counted whole
+	if last.Day != 76 {
+		t.Errorf("day = %.2f, expected 76", last.Day)
+	}
+	if last.Week != 87.5 || last.Month != 87.5 {
+		t.Errorf("week = %.2f, month = %.2f, expected 87.5", last.Week, last.Month)
+	}
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     float64
+	}{
+		{"first day", start, start.Add(24 * time.Hour), 100},
+		{"second day", start.Add(24 * time.Hour), start.Add(48 * time.Hour), 75},
+		{"outage", start.Add(30 * time.Hour), start.Add(36 * time.Hour), 0},
+		{"partial buckets", start.Add(29*time.Hour + 30*time.Minute), start.Add(30*time.Hour + 30*time.Minute), 50},
+		{"no queries", start.Add(-time.Hour), start, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := u.percent("a", tt.from, tt.to); got != tt.want {
+				t.Errorf("percent = %.2f, expected %.2f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUptimeTrackerDownsampled(t *testing.T) {
+	u := &UptimeTracker{servers: make(map[string][]uptimeCount)}
+	at := time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+	u.record("a", Sample{Time: at, Online: true, Count: 8, Up: 6})
+	up := u.record("a", Sample{Time: at.Add(time.Minute), Count: 2})
+
+	if up.Day != 60 {
+		t.Errorf("day = %.2f, expected 60", up.Day)
+	}
+}
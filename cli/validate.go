@@ -0,0 +1,477 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/woozymasta/a2s/pkg/a2s"
+)
+
+// Permissions the bot needs in the channels by their purpose
+const (
+	permsChannel = discordgo.PermissionViewChannel | discordgo.PermissionManageChannels
+	permsMessage = discordgo.PermissionViewChannel | discordgo.PermissionSendMessages | discordgo.PermissionEmbedLinks
+	permsAlerts  = discordgo.PermissionViewChannel | discordgo.PermissionSendMessages
+)
+
+// permissionNames are the names of permissions checked by validation, as shown in Discord
+var permissionNames = map[int64]string{
+	discordgo.PermissionViewChannel:    "View Channel",
+	discordgo.PermissionManageChannels: "Manage Channels",
+	discordgo.PermissionSendMessages:   "Send Messages",
+	discordgo.PermissionEmbedLinks:     "Embed Links",
+}
+
+// textChannelTypes are the channel types the bot can post messages to
+var textChannelTypes = []discordgo.ChannelType{
+	discordgo.ChannelTypeGuildText,
+	discordgo.ChannelTypeGuildNews,
+	discordgo.ChannelTypeGuildVoice,
+}
+
+/*
+Validator checks the configuration and reports all found problems.
+
+Errors make the configuration unusable or break rendering, warnings point
+to templates that fail only in some cases, like while a server is offline.
+*/
+type Validator struct {
+	out      io.Writer
+	ds       *discordgo.Session
+	guilds   map[string]*discordgo.Guild  // Guilds by ID, cached for permission checks
+	members  map[string]*discordgo.Member // Bot member by guild ID
+	userID   string                       // Bot user ID
+	errors   int
+	warnings int
+}
+
+// templateCheck is one configured template with sample data to execute it
+type templateCheck struct {
+	name    string // Path of the template in configuration
+	tplStr  string // Template string
+	online  any    // Sample data while the server is online, nil to skip
+	offline any    // Sample data while the server is offline, nil to skip
+}
+
+/*
+runValidate checks the configuration, templates and optionally Discord channels and exits.
+
+Arguments are an optional --discord flag to log in and check channels and
+permissions, and an optional path to the configuration file.
+The exit code is 1 if any error is found.
+*/
+func runValidate(args []string) {
+	var discord bool
+	for _, arg := range args {
+		switch arg {
+		case "--discord", "-d":
+			discord = true
+		default:
+			configFile = arg
+		}
+	}
+
+	v := &Validator{out: os.Stdout}
+	v.validate(discord)
+
+	fmt.Fprintf(v.out, "\n%d error(s), %d warning(s)\n", v.errors, v.warnings)
+	if v.errors > 0 {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// validate runs all checks
+func (v *Validator) validate(discord bool) {
+	cfg, err := parseConfig()
+	if err != nil {
+		v.errorf("config", "%v", err)
+		return
+	}
+	v.okf("config", "parsed %d server(s) and %d additional bot(s)", len(cfg.Servers), len(cfg.Bots))
+
+	base, err := newTemplateBase(cfg.Templates)
+	if err != nil {
+		v.errorf("templates", "%v", err)
+		return
+	}
+	if len(cfg.Templates) > 0 {
+		v.okf("templates", "parsed %d shared template(s)", len(cfg.Templates))
+	}
+
+	for _, check := range cfg.templateChecks() {
+		v.checkTemplate(base, check)
+	}
+
+	if discord {
+		v.checkDiscord(cfg)
+	}
+}
+
+// templateChecks collects all configured templates with sample data of matching type
+func (c *Config) templateChecks() []templateCheck {
+	var checks []templateCheck
+
+	online := make([]*TemplateData, len(c.Servers))
+	offline := make([]*TemplateData, len(c.Servers))
+	byID := make(map[string]int, len(c.Servers))
+
+	for i := range c.Servers {
+		srv := &c.Servers[i]
+		online[i], offline[i] = srv.sampleData(&c.History)
+		byID[srv.ID] = i
+		path := "servers." + srv.ID
+
+		checks = append(checks,
+			templateCheck{path + ".channel_name", srv.ChannelName, online[i], offline[i]},
+			templateCheck{path + ".channel_description", srv.ChannelDesc, online[i], offline[i]},
+			templateCheck{path + ".category_name", srv.CategoryName, online[i], offline[i]},
+		)
+		checks = append(checks, srv.StatusMessage.templateChecks(path+".status_message", online[i], offline[i])...)
+	}
+
+	// Alerts and player events are rendered with data of any server, sample of the first one is enough
+	if len(c.Servers) > 0 {
+		checks = append(checks,
+			templateCheck{"alerts.offline_message", c.Alerts.OfflineMessage, nil, &AlertData{TemplateData: offline[0]}},
+			templateCheck{"alerts.online_message", c.Alerts.OnlineMessage, &AlertData{TemplateData: online[0]}, nil},
+			templateCheck{"player_events.join_message", c.PlayerEvents.JoinMessage, &PlayerEventData{TemplateData: online[0]}, nil},
+			templateCheck{"player_events.leave_message", c.PlayerEvents.LeaveMessage, &PlayerEventData{TemplateData: online[0]}, nil},
+		)
+	}
+
+	sumOnline := &SummaryData{Stats: statsOf(online), Servers: online}
+	sumOffline := &SummaryData{Stats: statsOf(offline), Servers: offline}
+	checks = append(checks, c.StatusMessage.templateChecks("status_message", sumOnline, sumOffline)...)
+
+	paths := []string{"bot.presence"}
+	presences := []*Presence{&c.Bot.Presence}
+	for i := range c.Bots {
+		paths = append(paths, fmt.Sprintf("bots.%d.presence", i))
+		presences = append(presences, &c.Bots[i].Presence)
+	}
+	for k, p := range presences {
+		path := paths[k]
+		checks = append(checks,
+			templateCheck{path + ".online", p.Online, sumOnline, nil},
+			templateCheck{path + ".offline", p.Offline, nil, sumOffline},
+		)
+
+		for j, r := range p.Rotate {
+			name := fmt.Sprintf("%s.rotate.%d.template", path, j)
+			switch i, ok := byID[r.Server]; {
+			case ok:
+				checks = append(checks, templateCheck{name, r.Template, online[i], offline[i]})
+			case r.Server == rotateEachServer && len(c.Servers) > 0:
+				checks = append(checks, templateCheck{name, r.Template, online[0], offline[0]})
+			default:
+				checks = append(checks, templateCheck{name, r.Template, sumOnline, sumOffline})
+			}
+		}
+	}
+
+	return checks
+}
+
+// templateChecks collects the templates of the status message
+func (m *StatusMessage) templateChecks(path string, online, offline any) []templateCheck {
+	if m == nil {
+		return nil
+	}
+
+	checks := []templateCheck{
+		{path + ".title", m.Title, online, offline},
+		{path + ".description", m.Description, online, offline},
+		{path + ".color", m.Color, online, offline},
+		{path + ".footer", m.Footer, online, offline},
+	}
+	for i, f := range m.Fields {
+		checks = append(checks,
+			templateCheck{fmt.Sprintf("%s.fields.%d.name", path, i), f.Name, online, offline},
+			templateCheck{fmt.Sprintf("%s.fields.%d.value", path, i), f.Value, online, offline},
+		)
+	}
+
+	return checks
+}
+
+/*
+sampleData returns the template data of the server as it looks while online and offline.
+
+Values are empty, but have the right types, so typos in field names fail the execution.
+Extra is an empty map because its type depends on the game. History has all
+configured windows, so unknown window names fail too.
+*/
+func (s *ServerConfig) sampleData(h *HistoryConfig) (*TemplateData, *TemplateData) {
+	online := &TemplateData{
+		ID:      s.ID,
+		Host:    s.Host,
+		Port:    s.Port,
+		Info:    &a2s.Info{},
+		Extra:   map[string]any{},
+		Rules:   map[string]string{},
+		History: h.empty(),
+		Uptime:  &Uptime{},
+	}
+	offline := &TemplateData{ID: s.ID, Host: s.Host, Port: s.Port, History: h.empty(), Uptime: &Uptime{}}
+
+	return online, offline
+}
+
+// checkTemplate compiles the template with shared templates and executes it with the sample data
+func (v *Validator) checkTemplate(base *template.Template, check templateCheck) {
+	if check.tplStr == "" {
+		return
+	}
+
+	tmpl, err := compileTemplate(base, check.name, check.tplStr)
+	if err != nil {
+		v.errorf(check.name, "%v", err)
+		return
+	}
+
+	if check.online != nil {
+		if err := tmpl.Execute(io.Discard, check.online); err != nil {
+			v.errorf(check.name, "%v", err)
+			return
+		}
+	}
+	if check.offline != nil {
+		if err := tmpl.Execute(io.Discard, check.offline); err != nil {
+			v.warnf(check.name, "fails while the server is offline: %v", err)
+			return
+		}
+	}
+
+	v.okf(check.name, "template is valid")
+}
+
+/*
+checkDiscord logs in to Discord and checks that the configured channels exist,
+have the right type and the bot has the needed permissions there.
+
+Channels of servers bound to additional bots are checked with the bot of the server.
+Only REST requests are used, the gateway session is not opened.
+*/
+func (v *Validator) checkDiscord(cfg *Config) {
+	if !v.login("bot.token", cfg.Bot.Token) {
+		return
+	}
+
+	owned := make(map[string]struct{})
+	for i, bot := range cfg.Bots {
+		for _, id := range bot.Servers {
+			owned[id] = struct{}{}
+		}
+
+		sub := &Validator{out: v.out}
+		if sub.login(fmt.Sprintf("bots.%d.token", i), bot.Token) {
+			for _, srv := range cfg.Servers {
+				if !slices.Contains(bot.Servers, srv.ID) {
+					continue
+				}
+				sub.checkServer(&srv)
+				if srv.AlertsChanID == "" {
+					sub.checkChannel("alerts.channel_id", cfg.Alerts.ChannelID, textChannelTypes, permsAlerts)
+				}
+			}
+		}
+		v.errors += sub.errors
+		v.warnings += sub.warnings
+	}
+
+	// Player events of all servers are sent by the main bot
+	for _, srv := range cfg.Servers {
+		if _, ok := owned[srv.ID]; !ok {
+			v.checkServer(&srv)
+		}
+		v.checkChannel("servers."+srv.ID+".player_events_channel_id", srv.EventsChanID, textChannelTypes, permsAlerts)
+	}
+
+	v.checkChannel("alerts.channel_id", cfg.Alerts.ChannelID, textChannelTypes, permsAlerts)
+	v.checkChannel("player_events.channel_id", cfg.PlayerEvents.ChannelID, textChannelTypes, permsAlerts)
+	if cfg.StatusMessage != nil {
+		v.checkChannel("status_message.channel_id", cfg.StatusMessage.ChannelID, textChannelTypes, permsMessage)
+	}
+
+	for i, r := range cfg.Reports {
+		v.checkChannel(fmt.Sprintf("reports.%d.channel_id", i), r.ChannelID, textChannelTypes, permsMessage)
+	}
+}
+
+// login logs in with the token and prepares the validator for channel checks, false if it failed
+func (v *Validator) login(path, token string) bool {
+	ds, err := discordgo.New("Bot " + token)
+	if err != nil {
+		v.errorf(path, "%v", err)
+		return false
+	}
+
+	user, err := ds.User("@me")
+	if err != nil {
+		v.errorf(path, "failed to log in: %v", err)
+		return false
+	}
+	v.okf(path, "logged in as %s", user.Username)
+
+	v.ds = ds
+	v.userID = user.ID
+	v.guilds = make(map[string]*discordgo.Guild)
+	v.members = make(map[string]*discordgo.Member)
+
+	return true
+}
+
+// checkServer checks the channels edited and posted to by the bot bound to the server
+func (v *Validator) checkServer(srv *ServerConfig) {
+	path := "servers." + srv.ID
+	v.checkChannel(path+".channel_id", srv.ChannelID, nil, permsChannel)
+	v.checkChannel(path+".category_id", srv.CategoryID, []discordgo.ChannelType{discordgo.ChannelTypeGuildCategory}, permsChannel)
+	v.checkChannel(path+".alerts_channel_id", srv.AlertsChanID, textChannelTypes, permsAlerts)
+	if srv.StatusMessage != nil {
+		v.checkChannel(path+".status_message.channel_id", srv.StatusMessage.ChannelID, textChannelTypes, permsMessage)
+	}
+}
+
+/*
+checkChannel checks that the channel exists, has one of the types and the bot has the permissions.
+
+Empty types mean any guild channel except category.
+*/
+func (v *Validator) checkChannel(path, id string, types []discordgo.ChannelType, perms int64) {
+	if id == "" {
+		return
+	}
+
+	ch, err := v.ds.Channel(id)
+	if err != nil {
+		v.errorf(path, "channel %s not found or not accessible: %v", id, err)
+		return
+	}
+
+	switch {
+	case ch.GuildID == "":
+		v.errorf(path, "channel %s is not a guild channel", id)
+		return
+	case len(types) == 0 && ch.Type == discordgo.ChannelTypeGuildCategory:
+		v.errorf(path, "channel %s is a category, expected a channel", id)
+		return
+	case len(types) > 0 && !slices.Contains(types, ch.Type):
+		v.errorf(path, "channel %s has unexpected type %d", id, ch.Type)
+		return
+	}
+
+	guild, member, err := v.guild(ch.GuildID)
+	if err != nil {
+		v.errorf(path, "failed to get guild %s: %v", ch.GuildID, err)
+		return
+	}
+
+	if missing := perms &^ channelPermissions(guild, member, v.userID, ch); missing != 0 {
+		v.errorf(path, "missing permissions in #%s: %s", ch.Name, permissionList(missing))
+		return
+	}
+
+	v.okf(path, "#%s is accessible", ch.Name)
+}
+
+// guild returns the guild and the bot member in it, results are cached
+func (v *Validator) guild(id string) (*discordgo.Guild, *discordgo.Member, error) {
+	if guild, ok := v.guilds[id]; ok {
+		return guild, v.members[id], nil
+	}
+
+	guild, err := v.ds.Guild(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	member, err := v.ds.GuildMember(id, v.userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	v.guilds[id] = guild
+	v.members[id] = member
+
+	return guild, member, nil
+}
+
+/*
+channelPermissions calculates permissions of the member in the channel.
+
+It applies role permissions and then channel overwrites for @everyone,
+member roles and the member itself, the same way Discord does.
+*/
+func channelPermissions(guild *discordgo.Guild, member *discordgo.Member, userID string, ch *discordgo.Channel) int64 {
+	if guild.OwnerID == userID {
+		return discordgo.PermissionAll
+	}
+
+	var perms int64
+	for _, role := range guild.Roles {
+		if role.ID == guild.ID || slices.Contains(member.Roles, role.ID) {
+			perms |= role.Permissions
+		}
+	}
+	if perms&discordgo.PermissionAdministrator != 0 {
+		return discordgo.PermissionAll
+	}
+
+	for _, ow := range ch.PermissionOverwrites {
+		if ow.Type == discordgo.PermissionOverwriteTypeRole && ow.ID == guild.ID {
+			perms = perms&^ow.Deny | ow.Allow
+		}
+	}
+
+	var allow, deny int64
+	for _, ow := range ch.PermissionOverwrites {
+		if ow.Type == discordgo.PermissionOverwriteTypeRole && slices.Contains(member.Roles, ow.ID) {
+			allow |= ow.Allow
+			deny |= ow.Deny
+		}
+	}
+	perms = perms&^deny | allow
+
+	for _, ow := range ch.PermissionOverwrites {
+		if ow.Type == discordgo.PermissionOverwriteTypeMember && ow.ID == userID {
+			perms = perms&^ow.Deny | ow.Allow
+		}
+	}
+
+	return perms
+}
+
+// permissionList returns the names of the permissions joined with commas
+func permissionList(perms int64) string {
+	var names []string
+	for bit, name := range permissionNames {
+		if perms&bit != 0 {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	return strings.Join(names, ", ")
+}
+
+// okf prints the passed check
+func (v *Validator) okf(path, format string, args ...any) {
+	fmt.Fprintf(v.out, "ok       %s: %s\n", path, fmt.Sprintf(format, args...))
+}
+
+// warnf prints the warning and counts it
+func (v *Validator) warnf(path, format string, args ...any) {
+	v.warnings++
+	fmt.Fprintf(v.out, "warning  %s: %s\n", path, fmt.Sprintf(format, args...))
+}
+
+// errorf prints the error and counts it
+func (v *Validator) errorf(path, format string, args ...any) {
+	v.errors++
+	fmt.Fprintf(v.out, "error    %s: %s\n", path, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestValidateTemplates(t *testing.T) {
+	useConfig(t, `
+bot:
+  token: x
+servers:
+  - id: srv
+    channel_name: "{{ .Info.Players }} online"
+    channel_description: "{{ .Info.Nmae }}"
+    category_name: "{{ .ID }}"
+`)
+
+	var out bytes.Buffer
+	v := &Validator{out: &out}
+	v.validate(false)
+
+	// Typo in the field name is an error, accessing Info while offline is a warning
+	if v.errors != 1 || v.warnings != 1 {
+		t.Errorf("%d error(s), %d warning(s), expected 1 and 1:\n%s", v.errors, v.warnings, out.String())
+	}
+	for _, want := range []string{
+		"error    servers.srv.channel_description:",
+		"warning  servers.srv.channel_name: fails while the server is offline",
+		"ok       servers.srv.category_name: template is valid",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output does not contain %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestValidateConfigError(t *testing.T) {
+	useConfig(t, "bot:\n  token: x\nservers:\n  - id: a\n  - id: a\n")
+
+	var out bytes.Buffer
+	v := &Validator{out: &out}
+	v.validate(false)
+
+	if v.errors != 1 || !strings.Contains(out.String(), "error    config: duplicate server id") {
+		t.Errorf("%d error(s):\n%s", v.errors, out.String())
+	}
+}
+
+func TestChannelPermissions(t *testing.T) {
+	const (
+		guildID = "1"
+		userID  = "2"
+		roleID  = "3"
+	)
+	guild := &discordgo.Guild{ID: guildID, OwnerID: "9", Roles: []*discordgo.Role{
+		{ID: guildID, Permissions: discordgo.PermissionViewChannel},
+		{ID: roleID, Permissions: discordgo.PermissionSendMessages},
+	}}
+	member := &discordgo.Member{Roles: []string{roleID}}
+	overwrite := func(id string, typ discordgo.PermissionOverwriteType, allow, deny int64) *discordgo.PermissionOverwrite {
+		return &discordgo.PermissionOverwrite{ID: id, Type: typ, Allow: allow, Deny: deny}
+	}
+
+	tests := []struct {
+		name       string
+		overwrites []*discordgo.PermissionOverwrite
+		want       int64
+	}{
+		{"roles", nil, discordgo.PermissionViewChannel | discordgo.PermissionSendMessages},
+		{
+			"everyone denied",
+			[]*discordgo.PermissionOverwrite{overwrite(guildID, discordgo.PermissionOverwriteTypeRole, 0, discordgo.PermissionViewChannel)},
+			discordgo.PermissionSendMessages,
+		},
+		{
+			"role allows over everyone",
+			[]*discordgo.PermissionOverwrite{
+				overwrite(guildID, discordgo.PermissionOverwriteTypeRole, 0, discordgo.PermissionViewChannel),
+				overwrite(roleID, discordgo.PermissionOverwriteTypeRole, discordgo.PermissionViewChannel, 0),
+			},
+			discordgo.PermissionViewChannel | discordgo.PermissionSendMessages,
+		},
+		{
+			"member denies over role",
+			[]*discordgo.PermissionOverwrite{
+				overwrite(roleID, discordgo.PermissionOverwriteTypeRole, discordgo.PermissionEmbedLinks, 0),
+				overwrite(userID, discordgo.PermissionOverwriteTypeMember, 0, discordgo.PermissionSendMessages),
+			},
+			discordgo.PermissionViewChannel | discordgo.PermissionEmbedLinks,
+		},
+	}
+
+	for _, tt := range tests {
+		got := channelPermissions(guild, member, userID, &discordgo.Channel{PermissionOverwrites: tt.overwrites})
+		if got != tt.want {
+			t.Errorf("%s: permissions = %s, expected %s", tt.name, permissionList(got), permissionList(tt.want))
+		}
+	}
+
+	if got := channelPermissions(&discordgo.Guild{OwnerID: userID}, member, userID, &discordgo.Channel{}); got != discordgo.PermissionAll {
+		t.Errorf("owner permissions = %d, expected all", got)
+	}
+}
+
+func TestPermissionList(t *testing.T) {
+	if got := permissionList(permsMessage); got != "Embed Links, Send Messages, View Channel" {
+		t.Errorf("permissions = %q", got)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -18,15 +19,21 @@ type ChannelUpdateTask struct {
 	Tpl    *TemplateData
 }
 
+// discordTimeout limits the duration of blocking Discord API calls
+const discordTimeout = 30 * time.Second
+
 // channelUpdateQueue is a buffered channel to store update tasks
 var channelUpdateQueue = make(chan ChannelUpdateTask, 100)
 
 /*
 startUpdateWorkers launches 'workerCount' goroutines that read tasks from channelUpdateQueue
-and process them. Each task updates the server's channel/category in a blocking call, but
-this doesn't block the main update() because it's done asynchronously.
+and process them, and starts the channelScheduler that sends channel edits with the same concurrency.
+Each task renders the server's channel/category and schedules edits, this doesn't block
+the main update() because it's done asynchronously.
 */
 func startUpdateWorkers(ds *discordgo.Session, workerCount int, timeout time.Duration) {
+	channelScheduler.start(ds, workerCount, timeout)
+
 	for i := 0; i < workerCount; i++ {
 		go func() {
 			for task := range channelUpdateQueue {
@@ -37,40 +44,29 @@ func startUpdateWorkers(ds *discordgo.Session, workerCount int, timeout time.Dur
 }
 
 /*
-processChannelUpdate handles channel and category updates for one server config.
-It uses a context with timeout to avoid being stuck if Discord is slow.
+processChannelUpdate handles channel, category and status message updates for one server config.
+Channel and category edits are passed to the channelScheduler to respect Discord rate limits.
 */
 func processChannelUpdate(ds *discordgo.Session, task ChannelUpdateTask, timeout time.Duration) {
 	if ds == nil || task.Server == nil || task.Tpl == nil {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-
-	// Update channel
-	if err := task.Server.updateChannel(ctx, ds, task.Tpl); err != nil {
-		log.Error().
-			Err(err).
-			Str("server", task.Server.ID).
-			Msg("Failed to update channel for server")
-	}
+	// Update channel and category
+	task.Server.updateChannel(task.Tpl)
+	task.Server.updateCategory(task.Tpl)
 
-	// Update category
-	if err := task.Server.updateCategory(ctx, ds, task.Tpl); err != nil {
-		log.Error().
-			Err(err).
-			Str("server", task.Server.ID).
-			Msgf("Failed to update category for server")
-	}
+	// Update status message by the bot bound to the server
+	task.Server.StatusMessage.process(botSession(ds, activeConfig.Load(), task.Server.ID), task.Tpl, func() *discordgo.MessageEmbed {
+		return task.Tpl.statusEmbed(time.Now())
+	}, timeout)
 }
 
 /*
 editChannel is a context-aware function that edits the channel's name/topic.
 
-By default, discordgo doesn't have ChannelEditContext,
-so we manually check ctx.Done() before and after the request.
-You could also replace the http.Client in the session if you want real cancellation.
+Automatic retry of discordgo on rate limit is disabled, it would sleep for the whole
+Retry-After, instead *discordgo.RateLimitError is returned and handled by the scheduler.
 */
 func editChannel(ctx context.Context, ds *discordgo.Session, id, name, description string) error {
 	if id == "" {
@@ -96,12 +92,6 @@ func editChannel(ctx context.Context, ds *discordgo.Session, id, name, descripti
 		Str("description", description).
 		Msg("Preparing to edit channel/category")
 
-	select {
-	case <-ctx.Done():
-		return fmt.Errorf("edit channel canceled before request")
-	default:
-	}
-
 	ce := &discordgo.ChannelEdit{}
 	if name != "" {
 		ce.Name = name
@@ -110,23 +100,20 @@ func editChannel(ctx context.Context, ds *discordgo.Session, id, name, descripti
 		ce.Topic = description
 	}
 
-	_, err := ds.ChannelEdit(id, ce)
-	respErr, ok := err.(*discordgo.RESTError)
-	if ok && respErr.Response != nil && respErr.Response.StatusCode == 429 {
-		headers := respErr.Response.Header
+	_, err := ds.ChannelEdit(id, ce, discordgo.WithContext(ctx), discordgo.WithRetryOnRatelimit(false))
 
-		log.Warn().
-			Str("channel", id).
-			Str("retry_after", headers.Get("Retry-After")).
-			Str("limit", headers.Get("X-RateLimit-Limit")).
-			Str("remaining", headers.Get("X-RateLimit-Remaining")).
-			Msg("Discord 429 rate limit hit")
+	var rlErr *discordgo.RateLimitError
+	switch {
+	case errors.As(err, &rlErr):
+		metricChannelEdits.WithLabelValues(resultRateLimited).Inc()
+	case err != nil:
+		metricChannelEdits.WithLabelValues(resultError).Inc()
+	default:
+		metricChannelEdits.WithLabelValues(resultSuccess).Inc()
 	}
 
-	select {
-	case <-ctx.Done():
-		return fmt.Errorf("edit channel canceled after request")
-	default:
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("edit channel canceled: %w", ctx.Err())
 	}
 
 	return err